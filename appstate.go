@@ -2,9 +2,10 @@ package glutils
 
 import (
 	"container/list"
-	"github.com/banthar/Go-SDL/sdl"
-	"github.com/pzsz/gl"
 	"flag"
+	"github.com/banthar/Go-SDL/sdl"
+	"log"
+	"math"
 	"os"
 	"runtime/pprof"
 )
@@ -29,58 +30,293 @@ type AppState interface {
 	OnViewportResize(x, y float32)
 }
 
-type AppStateManager struct {
-	StateStack              *list.List
-	Screen                  *sdl.Surface
-	LastMouseX, LastMouseY  float32    
-	MouseSampleTaken        bool
+// Optional interface for states that draw separately from Process.
+// Alpha is the interpolation factor between the last two fixed
+// steps, in variable step mode it is always 1. Manager draws only when
+// all visible states implement it, an overlay over state drawing in
+// Process has to draw there as well.
+type RenderingState interface {
+	Render(alpha float32)
+}
 
-	FPSMouseModeEnabled     bool
+// Optional interface for states drawn over other states, like pause
+// menus. When RenderBelow returns true states beneath are rendered first.
+type OverlayState interface {
+	RenderBelow() bool
 }
 
-var AppStateManagerInstance *AppStateManager = &AppStateManager{StateStack: list.New()}
+// Optional interface for states that let states beneath keep running,
+// like HUDs or dialogs over gameplay. Lower states are processed
+// before the upper ones, even though they got Pause call.
+type UpdateTransparentState interface {
+	UpdateBelow() bool
+}
+
+// Optional interface for states passing input through. It is asked
+// after the state handled the event, returning true means the event
+// wasn't consumed and should be given to the state beneath.
+type InputTransparentState interface {
+	InputBelow(event sdl.Event) bool
+}
+
+type AppStateManager struct {
+	StateStack *list.List
+	// Window and input backend, SDL one is used when left nil
+	Platform Platform
+	// Window surface with SDLPlatform, nil with other platforms. Kept
+	// for old code, Platform.WindowSize works with any of them.
+	Screen *sdl.Surface
+	// Action bindings, fed with events before they reach states
+	Input *InputMap
+	// Wheel, drag, double click and hover events
+	Pointer *PointerTracker
+
+	// Input recording and playback, see StartRecording and StartReplay
+	Recorder *InputRecorder
+	Replayer *InputReplayer
+	// Stop RunLoop when replay is over
+	QuitAfterReplay bool
+	// Number of frames run so far
+	FrameIndex uint32
+	// Sum of time steps so far, in seconds
+	Time float32
+
+	LastMouseX, LastMouseY float32
+	MouseSampleTaken       bool
+
+	FPSMouseModeEnabled bool
+	// Timers and sequences, updated along with Process
+	Scheduler *Scheduler
+	// Gamepad state, devices are opened in Setup
+	Gamepads *GamepadManager
+	// Filtering of relative movement in FPS mouse mode
+	Mouse *RelativeMouse
+
+	// Text input mode, see SetTextInput
+	TextInputEnabled bool
+	// Source of text for paste operation, if any
+	Clipboard func() string
+
+	// Video mode used by Setup, kept up to date on changes
+	Window WindowConfig
+	// Limit of frames per second, 0 means no limit
+	MaxFPS int
+	// Frame times, updated after each frame
+	Stats FrameStats
+
+	// Destroy GL objects created while state was on top when it's
+	// removed, see ResourceRegistry
+	FreeStateResources bool
+
+	// Reloads changed asset files between frames, disabled when nil
+	Watcher *AssetWatcher
+
+	// Developer console toggled with ConsoleKey, disabled when nil
+	Console    *ConsoleState
+	ConsoleKey uint32
+
+	// Fixed step mode, see SetFixedStep
+	FixedStep        bool
+	StepTime         float32
+	MaxStepsPerFrame int
+
+	lastTicks   uint32
+	accumulator float32
+
+	textInput textInput
+
+	inFrame    bool
+	pending    []stateOp
+	transition *runningTransition
+	// State whose callback runs now
+	current AppState
+
+	consoleKeyHeld bool
+
+	dispatched dispatchQueue
+}
+
+var AppStateManagerInstance *AppStateManager = NewAppStateManager()
+
+// Manager with default subsystems. Code outside of tests uses the one
+// returned by GetManager.
+func NewAppStateManager() *AppStateManager {
+	return &AppStateManager{
+		StateStack: list.New(),
+		Input:      NewInputMap(),
+		Pointer:    NewPointerTracker(),
+		Mouse:      NewRelativeMouse(),
+		Gamepads:   NewGamepadManager(),
+		Scheduler:  NewScheduler(),
+		Window:     DefaultWindowConfig(),
+		ConsoleKey: sdl.K_BACKQUOTE}
+}
 
 func GetManager() *AppStateManager {
 	return AppStateManagerInstance
 }
 
-func (self *AppStateManager) Setup(state AppState, caption string) {
+// Open window using Window config and push the first state. Calling
+// goroutine is locked to its OS thread and has to run RunLoop.
+func (self *AppStateManager) Setup(state AppState, caption string) error {
+	lockMainThread()
+
 	if *FLAG_profile {
 		pfile, _ := os.Create("gowar.prof")
 		pprof.StartCPUProfile(pfile)
 	}
 
-	// Lock, so we got no GL-in-wrong-thread foolery
-	sdl.Init(sdl.INIT_VIDEO)
+	if self.Platform == nil {
+		self.Platform = NewSDLPlatform()
+	}
+
+	if err := self.Platform.OpenWindow(caption, self.Window); err != nil {
+		return err
+	}
+	// Platform may give other size than asked for
+	self.Window.Width, self.Window.Height = self.Platform.WindowSize()
 
-	self.Screen = sdl.SetVideoMode(800, 600, 32, sdl.OPENGL|sdl.RESIZABLE)
+	self.updateViewport()
+	self.RescanGamepads()
 
-	gl.Init()
+	self.Push(state)
+	return nil
+}
 
-	if self.Screen == nil {
-		sdl.Quit()
-		panic("Couldn't set 300x300 GL video mode: " + sdl.GetError() + "\n")
+// Switch video mode, states are notified with OnViewportResize. On
+// failure previous mode is restored. When that fails too
+// *VideoModeError is returned and it's up to the caller what to do
+// with window in unknown state.
+func (self *AppStateManager) SetVideoMode(config WindowConfig) error {
+	if err := self.Platform.SetVideoMode(config); err != nil {
+		if restoreErr := self.Platform.SetVideoMode(self.Window); restoreErr != nil {
+			return &VideoModeError{err, restoreErr}
+		}
+		return err
 	}
+	self.Window = config
 
-	sdl.WM_SetCaption(caption, caption)
+	w, h := self.updateViewport()
+	// Every state gets it, lower ones will be shown again later
+	for e := self.StateStack.Front(); e != nil; e = e.Next() {
+		e.Value.(AppState).OnViewportResize(w, h)
+	}
+	return nil
+}
 
-	Setup()
+func (self *AppStateManager) SetResolution(width, height int) error {
+	config := self.Window
+	config.Width, config.Height = width, height
+	return self.SetVideoMode(config)
+}
 
-	GetViewport().SetScreenSize(float32(self.Screen.W), float32(self.Screen.H))
+func (self *AppStateManager) SetFullscreen(on bool) error {
+	config := self.Window
+	config.Fullscreen = on
+	return self.SetVideoMode(config)
+}
 
-	self.Push(state)
+func (self *AppStateManager) ToggleFullscreen() error {
+	return self.SetFullscreen(!self.Window.Fullscreen)
+}
+
+func (self *AppStateManager) updateViewport() (w, h float32) {
+	if platform, ok := self.Platform.(*SDLPlatform); ok {
+		self.Screen = platform.Screen
+	}
+
+	sw, sh := self.Platform.WindowSize()
+	w, h = float32(sw), float32(sh)
+	GetViewport().SetSize(w, h)
+	return
 }
 
+// Hide and grab the pointer, feeding filtered relative movement to
+// RelativeMouseHandler states
 func (self *AppStateManager) FPSMouseMode(on bool) {
 	self.FPSMouseModeEnabled = on
-	if on {
-		sdl.ShowCursor(0)
-	} else {
-		sdl.ShowCursor(1)
+	self.Platform.SetRelativeMouse(on)
+	if self.Mouse != nil {
+		self.Mouse.Reset()
+	}
+}
+
+// Give filtered relative movement of this frame to the running state
+func (self *AppStateManager) updateRelativeMouse(time_step float32) {
+	if !self.FPSMouseModeEnabled || self.Mouse == nil {
+		return
+	}
+
+	dx, dy := self.Mouse.Update(time_step)
+	state := self.GetRunningState()
+	if handler, ok := state.(RelativeMouseHandler); ok {
+		self.runAs(state, func() { handler.OnRelativeMouse(dx, dy) })
+	}
+}
+
+// Stack operation, queued when requested in the middle of a frame
+type stateOp struct {
+	kind  int
+	state AppState
+}
+
+const (
+	opPush = iota
+	opPop
+	opReplace
+)
+
+// Apply operation at once, or queue it till the end of frame when
+// states are being run
+func (self *AppStateManager) queue(op stateOp) {
+	if self.inFrame {
+		self.pending = append(self.pending, op)
+		return
+	}
+
+	switch op.kind {
+	case opPush:
+		self.push(op.state)
+	case opPop:
+		self.pop()
+	case opReplace:
+		self.replace(op.state)
+	}
+}
+
+// Apply operations queued during frame, in order of request
+func (self *AppStateManager) applyPending() {
+	for len(self.pending) > 0 {
+		op := self.pending[0]
+		self.pending = self.pending[1:]
+		self.queue(op)
 	}
 }
 
+// Put state on top of the stack. Called during frame, the change
+// happens when the frame is finished.
 func (self *AppStateManager) Push(state AppState) {
+	self.queue(stateOp{opPush, state})
+}
+
+// Remove running state. Called during frame, the change happens when
+// the frame is finished and nil is returned.
+func (self *AppStateManager) Pop() AppState {
+	if self.inFrame {
+		self.queue(stateOp{opPop, nil})
+		return nil
+	}
+	return self.pop()
+}
+
+// Swap running state with a new one. Called during frame, the change
+// happens when the frame is finished.
+func (self *AppStateManager) Replace(state AppState) {
+	self.queue(stateOp{opReplace, state})
+}
+
+func (self *AppStateManager) push(state AppState) {
+	processed := self.processedStates()
 	e := self.StateStack.Back()
 	if e != nil {
 		prev := e.Value.(AppState)
@@ -88,36 +324,93 @@ func (self *AppStateManager) Push(state AppState) {
 	}
 
 	self.StateStack.PushBack(state)
-	state.Setup(self)
+	self.syncTimers(processed)
+	self.runAs(state, func() { state.Setup(self) })
 }
 
-func (self *AppStateManager) Pop() (ret AppState) {
+func (self *AppStateManager) pop() (ret AppState) {
+	processed := self.processedStates()
 	e := self.StateStack.Back()
 	if e != nil {
 		ret = e.Value.(AppState)
 		ret.Destroy()
 		self.StateStack.Remove(e)
+		self.forgetState(ret)
 	}
 
 	ne := self.StateStack.Back()
 	if ne != nil {
 		nstate := ne.Value.(AppState)
-		nstate.Resume()
+		self.runAs(nstate, nstate.Resume)
 	}
+	self.syncTimers(processed)
 
 	return
 }
 
-func (self *AppStateManager) Replace(state AppState) {
+func (self *AppStateManager) replace(state AppState) {
+	processed := self.processedStates()
 	e := self.StateStack.Back()
 	if e != nil {
 		ret := e.Value.(AppState)
 		ret.Destroy()
 		self.StateStack.Remove(e)
+		self.forgetState(ret)
 	}
 
 	self.StateStack.PushBack(state)
-	state.Setup(self)
+	self.syncTimers(processed)
+	self.runAs(state, func() { state.Setup(self) })
+}
+
+// Drop everything kept about destroyed state
+func (self *AppStateManager) forgetState(state AppState) {
+	if self.Pointer != nil {
+		self.Pointer.forgetState(state)
+	}
+	if self.Scheduler != nil {
+		self.Scheduler.CancelOwner(state)
+	}
+	if self.FreeStateResources {
+		GetResourceRegistry().FreeOwner(state)
+	}
+}
+
+// Pause timers of states that stopped being processed with stack
+// change and resume those that are processed again. States kept
+// running by UpdateBelow keep their timers running too.
+func (self *AppStateManager) syncTimers(before []AppState) {
+	if self.Scheduler == nil {
+		return
+	}
+
+	after := self.processedStates()
+	for e := self.StateStack.Front(); e != nil; e = e.Next() {
+		state := e.Value.(AppState)
+		was, is := containsState(before, state), containsState(after, state)
+		if was && !is {
+			self.Scheduler.Pause(state)
+		} else if is && !was {
+			self.Scheduler.Resume(state)
+		}
+	}
+}
+
+func containsState(states []AppState, state AppState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Run f as code of state, GL objects created meanwhile are owned by it
+func (self *AppStateManager) runAs(state AppState, f func()) {
+	prev := self.current
+	self.current = state
+	defer func() { self.current = prev }()
+	f()
 }
 
 func (self *AppStateManager) GetRunningState() AppState {
@@ -128,115 +421,402 @@ func (self *AppStateManager) GetRunningState() AppState {
 	return nil
 }
 
-func (self *AppStateManager) Process(time_step float32) {
-	e := self.StateStack.Back()
-	if e != nil {
+// Running state and states beneath it that keep simulating, from
+// the top of stack downwards
+func (self *AppStateManager) processedStates() (ret []AppState) {
+	for e := self.StateStack.Back(); e != nil; e = e.Prev() {
 		state := e.Value.(AppState)
-		state.Process(time_step)
+		ret = append(ret, state)
+
+		update, ok := state.(UpdateTransparentState)
+		if !ok || !update.UpdateBelow() {
+			break
+		}
+	}
+	return
+}
+
+// Process running state and states beneath it that keep simulating,
+// starting from the lowest one
+func (self *AppStateManager) Process(time_step float32) {
+	states := self.processedStates()
+	for i := len(states) - 1; i >= 0; i-- {
+		self.runAs(states[i], func() { states[i].Process(time_step) })
+	}
+
+	if self.Scheduler != nil {
+		self.Scheduler.Update(time_step)
+	}
+}
+
+// Clear buffers, render states and swap. Nothing is done when any of
+// visible states isn't RenderingState, as such states draw and swap
+// by themselves.
+func (self *AppStateManager) Render(alpha float32) {
+	states := self.renderedStates()
+	if len(states) == 0 {
+		return
+	}
+
+	self.renderStates(states, alpha)
+	if self.transition != nil {
+		self.transition.trans.Render(self.transition.progress())
+	}
+	self.Platform.SwapBuffers()
+}
+
+func (self *AppStateManager) renderStates(states []RenderingState, alpha float32) {
+	self.Platform.Clear()
+	for i := len(states) - 1; i >= 0; i-- {
+		state := states[i]
+		self.runAs(state.(AppState), func() { state.Render(alpha) })
 	}
 }
 
+// Visible states, from the top of stack downwards. Nil when some of
+// them draws by itself.
+func (self *AppStateManager) renderedStates() (ret []RenderingState) {
+	for e := self.StateStack.Back(); e != nil; e = e.Prev() {
+		state, ok := e.Value.(RenderingState)
+		if !ok {
+			return nil
+		}
+		ret = append(ret, state)
+
+		overlay, ok := e.Value.(OverlayState)
+		if !ok || !overlay.RenderBelow() {
+			break
+		}
+	}
+	return
+}
+
+// Make Process be called with constant time step, tickRate times per
+// second. At most maxSteps are run per frame, time that couldn't be
+// caught up with is dropped. tickRate of 0 goes back to variable step.
+func (self *AppStateManager) SetFixedStep(tickRate float32, maxSteps int) {
+	self.accumulator = 0
+	if tickRate <= 0 {
+		self.FixedStep = false
+		return
+	}
+
+	if maxSteps < 1 {
+		maxSteps = 1
+	}
+
+	self.FixedStep = true
+	self.StepTime = 1 / tickRate
+	self.MaxStepsPerFrame = maxSteps
+}
+
 func (self *AppStateManager) HandleEvents() (done bool) {
 	done = false
 
-	running_state := self.GetRunningState()
-
-	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+	for event := self.pollEvent(); event != nil; event = self.pollEvent() {
 		switch event.(type) {
 		case *sdl.QuitEvent:
+			self.record(event, 0, 0)
 			done = true
 			break
 		case *sdl.ResizeEvent:
+			self.record(event, 0, 0)
 			re := event.(*sdl.ResizeEvent)
-			self.Screen = sdl.SetVideoMode(int(re.W), int(re.H), 16,
-				sdl.OPENGL|sdl.RESIZABLE)
-			if self.Screen != nil {
-				GetViewport().SetScreenSize(float32(self.Screen.W), float32(self.Screen.H))
-
-				if running_state != nil {
-					running_state.OnViewportResize(float32(self.Screen.W), float32(self.Screen.H))
-				}
-
-			} else {
-				panic("we couldn't set the new video mode??")
+			// Window stays as it was when the new size can't be set
+			if err := self.SetResolution(int(re.W), int(re.H)); err != nil {
+				log.Println(err)
 			}
 			break
 
 		case *sdl.KeyboardEvent:
-			if running_state != nil {
-				kevent := event.(*sdl.KeyboardEvent)
+			self.record(event, 0, 0)
+			kevent := event.(*sdl.KeyboardEvent)
+			if self.Console != nil && kevent.Keysym.Sym == self.ConsoleKey {
+				// Key repeat sends presses without releases
+				if kevent.State == 1 && !self.consoleKeyHeld {
+					self.ToggleConsole()
+				}
+				self.consoleKeyHeld = kevent.State == 1
+				break
+			}
+			if self.TextInputEnabled && kevent.State == 1 &&
+				self.handleTextInput(event, &kevent.Keysym) {
+				break
+			}
+			self.feedInput(event)
+			self.dispatchInput(event, func(state AppState) {
 				if kevent.State == 1 {
-					running_state.OnKeyDown(&kevent.Keysym)
+					state.OnKeyDown(&kevent.Keysym)
 				} else {
-					running_state.OnKeyUp(&kevent.Keysym)
+					state.OnKeyUp(&kevent.Keysym)
 				}
-			}
+			})
 			break
 		case *sdl.MouseMotionEvent:
-			if running_state != nil {
-				mevent := event.(*sdl.MouseMotionEvent)
-				dx, dy := float32(0), float32(0)
-				fx, fy := float32(mevent.X), float32(mevent.Y)
-
-				if self.MouseSampleTaken {
-					dx = fx - self.LastMouseX
-					dy = fy - self.LastMouseY
-				} else {
-					self.MouseSampleTaken = true
-				}
-
-				running_state.OnMouseMove(
-					float32(mevent.X),
-					float32(mevent.Y), dx, dy)
+			mevent := event.(*sdl.MouseMotionEvent)
+			dx, dy := float32(0), float32(0)
+			fx, fy := float32(mevent.X), float32(mevent.Y)
+			// Replay gives the same motion even when started mid-session
+			replayed := false
+			if self.Replayer != nil {
+				dx, dy, replayed = self.Replayer.motionDelta(mevent)
+			}
 
-				if self.FPSMouseModeEnabled {
-					sdl.EventState(sdl.MOUSEMOTION, sdl.IGNORE)
-					sdl.WarpMouse(int(self.Screen.W/2), int(self.Screen.H/2))
-					sdl.EventState(sdl.MOUSEMOTION, sdl.ENABLE)
-					self.LastMouseX = float32(self.Screen.W/2)
-					self.LastMouseY = float32(self.Screen.H/2)
-				} else {
-					self.LastMouseX = fx
-					self.LastMouseY = fy
+			if self.FPSMouseModeEnabled {
+				// Pointer is grabbed, position doesn't change much
+				if !replayed {
+					dx, dy = float32(mevent.Xrel), float32(mevent.Yrel)
 				}
+				if self.Mouse != nil {
+					self.Mouse.AddRaw(dx, dy)
+				}
+			} else if self.MouseSampleTaken && !replayed {
+				dx = fx - self.LastMouseX
+				dy = fy - self.LastMouseY
+			}
+			self.MouseSampleTaken = true
+			self.record(event, dx, dy)
+
+			self.dispatchInput(event, func(state AppState) {
+				state.OnMouseMove(fx, fy, dx, dy)
+			})
+			if self.Pointer != nil {
+				self.Pointer.MotionEvent(self, event, fx, fy)
 			}
+
+			self.LastMouseX = fx
+			self.LastMouseY = fy
 			break
 		case *sdl.MouseButtonEvent:
-			if running_state != nil {
-				mevent := event.(*sdl.MouseButtonEvent)
-				running_state.OnMouseClick(float32(mevent.X),
+			self.record(event, 0, 0)
+			mevent := event.(*sdl.MouseButtonEvent)
+			self.feedInput(event)
+			self.dispatchInput(event, func(state AppState) {
+				state.OnMouseClick(float32(mevent.X),
 					float32(mevent.Y),
 					int(mevent.Button),
 					mevent.State == 1)
+			})
+			if self.Pointer != nil {
+				self.Pointer.ButtonEvent(self, event, float32(mevent.X),
+					float32(mevent.Y), int(mevent.Button),
+					mevent.State == 1, self.Time)
 			}
 			break
-		default:
-			if running_state != nil {
-				running_state.OnSdlEvent(&event)
+		case *sdl.JoyAxisEvent, *sdl.JoyButtonEvent, *sdl.JoyHatEvent:
+			self.record(event, 0, 0)
+			if self.Gamepads != nil {
+				self.Gamepads.handleEvent(self, event)
+			} else {
+				self.dispatchInput(event, func(state AppState) {
+					state.OnSdlEvent(&event)
+				})
+			}
+			break
+		case *gamepadsEvent:
+			// Devices of replayed recording
+			self.record(event, 0, 0)
+			if self.Gamepads != nil {
+				self.Gamepads.releaseAll(self)
+				self.Gamepads.setDevices(event.(*gamepadsEvent).Gamepads)
 			}
 			break
+		default:
+			self.dispatchInput(event, func(state AppState) {
+				state.OnSdlEvent(&event)
+			})
+			break
 		}
 	}
 	return
 }
 
+// Next event, during replay taken from recording. Live events of
+// recorded kinds are dropped then, except for quit requests.
+func (self *AppStateManager) pollEvent() sdl.Event {
+	if self.Replayer == nil {
+		return self.Platform.PollEvent()
+	}
+
+	for event := self.Platform.PollEvent(); event != nil; event = self.Platform.PollEvent() {
+		if _, quit := event.(*sdl.QuitEvent); quit || !recordedEvent(event) {
+			return event
+		}
+	}
+	return self.Replayer.PollEvent()
+}
+
+func (self *AppStateManager) record(event sdl.Event, dx, dy float32) {
+	if self.Recorder != nil {
+		self.Recorder.RecordEvent(event, dx, dy)
+	}
+}
+
+// Write every dispatched event and frame time step to file
+func (self *AppStateManager) StartRecording(filename string) error {
+	self.StopRecording()
+
+	recorder, err := CreateInputRecording(filename)
+	if err != nil {
+		return err
+	}
+	self.Recorder = recorder
+	if self.Gamepads != nil {
+		recorder.RecordGamepads(self.Gamepads.devices())
+	}
+	return nil
+}
+
+func (self *AppStateManager) StopRecording() error {
+	if self.Recorder == nil {
+		return nil
+	}
+	err := self.Recorder.Close()
+	self.Recorder = nil
+	return err
+}
+
+// Feed events and time steps from recording instead of live ones,
+// starting with the next frame
+func (self *AppStateManager) StartReplay(filename string) error {
+	replayer, err := OpenInputReplay(filename)
+	if err != nil {
+		return err
+	}
+	self.Replayer = replayer
+	return nil
+}
+
+// Live gamepads are reopened, as recording might have used others
+func (self *AppStateManager) StopReplay() {
+	if self.Replayer == nil {
+		return
+	}
+	self.Replayer = nil
+	if self.Platform != nil {
+		self.RescanGamepads()
+	}
+}
+
+func (self *AppStateManager) feedInput(event sdl.Event) {
+	if self.Input != nil {
+		self.Input.HandleEvent(event)
+	}
+}
+
+// Hand event to the running state, and further down the stack for as
+// long as states let it through
+func (self *AppStateManager) dispatchInput(event sdl.Event, handle func(state AppState)) {
+	for e := self.StateStack.Back(); e != nil; e = e.Prev() {
+		state := e.Value.(AppState)
+		self.runAs(state, func() { handle(state) })
+
+		input, ok := state.(InputTransparentState)
+		if !ok || !input.InputBelow(event) {
+			break
+		}
+	}
+}
+
 func (self *AppStateManager) RunLoop() {
 	done := false
-	last_ticks := sdl.GetTicks()
+	self.lastTicks = self.Platform.GetTicks()
 	for !done {
-		//		for ;sdl.GetTicks()-last_ticks < 10;time.Sleep(1) {}
-		current_ticks := sdl.GetTicks()
-		timeStepMS := current_ticks - last_ticks
-		time_step := float32(timeStepMS) / 1000.0
+		done = self.Frame()
+	}
+}
+
+// Run single iteration of main loop. Useful for driving manager
+// step by step with HeadlessPlatform.
+func (self *AppStateManager) Frame() (done bool) {
+	current_ticks := self.Platform.GetTicks()
+	timeStepMS := current_ticks - self.lastTicks
+	time_step := float32(timeStepMS) / 1000.0
+
+	if self.Replayer != nil {
+		replay_step, ok := self.Replayer.NextFrame()
+		if ok {
+			time_step = replay_step
+		} else {
+			self.StopReplay()
+			if self.QuitAfterReplay {
+				return true
+			}
+		}
+	}
+	if self.Recorder != nil {
+		self.Recorder.BeginFrame(self.FrameIndex, time_step)
+	}
 
-		done = self.HandleEvents()
+	self.inFrame = true
+	self.updateGamepads(time_step)
+	done = self.HandleEvents()
+	self.updateRelativeMouse(time_step)
+	if self.FixedStep {
+		alpha := self.processFixed(time_step)
+		self.updateTransition(time_step)
+		self.Render(alpha)
+	} else {
 		self.Process(time_step)
-		last_ticks = current_ticks
+		self.updateTransition(time_step)
+		self.Render(1)
+	}
+	self.inFrame = false
+	self.applyPending()
+	self.dispatched.run()
+	if self.Watcher != nil {
+		self.Watcher.Update(time_step)
+	}
+	if self.Input != nil && !self.FixedStep {
+		self.Input.EndFrame()
+	}
+	self.Stats.FrameFinished(int64(timeStepMS))
+	self.lastTicks = current_ticks
+	self.FrameIndex++
+	self.Time += time_step
+
+	if self.MaxFPS > 0 {
+		frameMS := 1000 / uint32(self.MaxFPS)
+		spent := self.Platform.GetTicks() - current_ticks
+		if spent < frameMS {
+			self.Platform.Delay(frameMS - spent)
+		}
 	}
+	return
+}
+
+// Run as many fixed steps as accumulated time allows, returns
+// interpolation alpha for rendering
+func (self *AppStateManager) processFixed(time_step float32) float32 {
+	self.accumulator += time_step
+
+	for steps := 0; self.accumulator >= self.StepTime; steps++ {
+		if steps == self.MaxStepsPerFrame {
+			// Spiral of death guard, we are too slow to keep up
+			self.accumulator = float32(math.Mod(float64(self.accumulator),
+				float64(self.StepTime)))
+			break
+		}
+
+		self.Process(self.StepTime)
+		self.accumulator -= self.StepTime
+		// Frames without a step keep the edges for the next one
+		if self.Input != nil {
+			self.Input.EndFrame()
+		}
+	}
+
+	return self.accumulator / self.StepTime
 }
 
 func (self *AppStateManager) Destroy() {
-	sdl.Quit()
+	self.StopRecording()
+	if *FLAG_glleaks {
+		if report := GetResourceRegistry().LeakReport(); report != "" {
+			log.Println("GL objects left alive:\n" + report)
+		}
+	}
+	self.Platform.Close()
 
 	if *FLAG_profile {
 		pprof.StopCPUProfile()
@@ -0,0 +1,536 @@
+package glutils
+
+import (
+	"fmt"
+	"github.com/banthar/Go-SDL/sdl"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// State writing its calls to shared log, so order across states can
+// be checked
+type testState struct {
+	name string
+	log  *[]string
+
+	updateBelow, renderBelow, inputBelow bool
+	// Time steps Process was called with
+	steps   []float32
+	process func(time_step float32)
+}
+
+func newTestState(name string, log *[]string) *testState {
+	return &testState{name: name, log: log}
+}
+
+func (self *testState) note(format string, args ...interface{}) {
+	*self.log = append(*self.log, self.name+" "+fmt.Sprintf(format, args...))
+}
+
+func (self *testState) Setup(man *AppStateManager) { self.note("setup") }
+func (self *testState) Destroy()                   { self.note("destroy") }
+func (self *testState) Pause()                     { self.note("pause") }
+func (self *testState) Resume()                    { self.note("resume") }
+
+func (self *testState) Process(time_step float32) {
+	self.steps = append(self.steps, time_step)
+	if self.process != nil {
+		self.process(time_step)
+	}
+}
+
+func (self *testState) OnKeyDown(key *sdl.Keysym) { self.note("key down %d", key.Sym) }
+func (self *testState) OnKeyUp(key *sdl.Keysym)   { self.note("key up %d", key.Sym) }
+
+func (self *testState) OnMouseMove(x, y, dx, dy float32) {
+	self.note("mouse move %v,%v %v,%v", x, y, dx, dy)
+}
+
+func (self *testState) OnMouseClick(x, y float32, button int, down bool) {
+	self.note("mouse click %v,%v %d %v", x, y, button, down)
+}
+
+func (self *testState) OnSdlEvent(event *sdl.Event) { self.note("event %T", *event) }
+
+func (self *testState) OnViewportResize(x, y float32) { self.note("resize %v,%v", x, y) }
+
+func (self *testState) UpdateBelow() bool               { return self.updateBelow }
+func (self *testState) RenderBelow() bool               { return self.renderBelow }
+func (self *testState) InputBelow(event sdl.Event) bool { return self.inputBelow }
+
+// testState drawing in Render instead of Process
+type renderTestState struct {
+	*testState
+	alphas []float32
+}
+
+func newRenderTestState(name string, log *[]string) *renderTestState {
+	return &renderTestState{testState: newTestState(name, log)}
+}
+
+func (self *renderTestState) Render(alpha float32) {
+	self.alphas = append(self.alphas, alpha)
+}
+
+// Fresh manager on headless platform, installed as the one returned by
+// GetManager for the length of the test
+func newTestManager(t *testing.T) (*AppStateManager, *HeadlessPlatform) {
+	platform := NewHeadlessPlatform(320, 240)
+	man := NewAppStateManager()
+	man.Platform = platform
+
+	prev := AppStateManagerInstance
+	AppStateManagerInstance = man
+	t.Cleanup(func() { AppStateManagerInstance = prev })
+	return man, platform
+}
+
+func keyEvent(sym uint32, down bool) *sdl.KeyboardEvent {
+	event := &sdl.KeyboardEvent{Type: sdl.KEYUP}
+	if down {
+		event.Type, event.State = sdl.KEYDOWN, 1
+	}
+	event.Keysym.Sym = sym
+	return event
+}
+
+func stackNames(man *AppStateManager) (ret []string) {
+	for e := man.StateStack.Front(); e != nil; e = e.Next() {
+		switch state := e.Value.(type) {
+		case *testState:
+			ret = append(ret, state.name)
+		case *renderTestState:
+			ret = append(ret, state.name)
+		}
+	}
+	return
+}
+
+func checkLog(t *testing.T, got *[]string, want ...string) {
+	t.Helper()
+	if len(*got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("log\n%q\nwant\n%q", *got, want)
+	}
+	*got = nil
+}
+
+func TestManagerSetup(t *testing.T) {
+	man, platform := newTestManager(t)
+	log := []string{}
+	a := newTestState("a", &log)
+
+	if err := man.Setup(a, "test"); err != nil {
+		t.Fatal(err)
+	}
+	checkLog(t, &log, "a setup")
+
+	if man.GetRunningState() != AppState(a) {
+		t.Error("first state isn't running")
+	}
+	if man.Window.Width != 320 || man.Window.Height != 240 {
+		t.Errorf("window %dx%d, want size of platform", man.Window.Width, man.Window.Height)
+	}
+	if vp := GetViewport(); vp.Width != 320 || vp.Height != 240 {
+		t.Errorf("viewport %vx%v", vp.Width, vp.Height)
+	}
+	if man.Screen != nil {
+		t.Error("headless platform has Screen")
+	}
+
+	man.Destroy()
+	if !platform.Closed {
+		t.Error("platform not closed")
+	}
+}
+
+func TestManagerFrame(t *testing.T) {
+	man, platform := newTestManager(t)
+	log := []string{}
+	a := newTestState("a", &log)
+	man.Setup(a, "test")
+	log = nil
+
+	platform.Advance(16)
+	if man.Frame() {
+		t.Error("frame reported done")
+	}
+	platform.Advance(20)
+	man.Frame()
+
+	if want := []float32{0.016, 0.02}; !reflect.DeepEqual(a.steps, want) {
+		t.Errorf("steps %v, want %v", a.steps, want)
+	}
+	if man.FrameIndex != 2 || !nearlyEqual(man.Time, 0.036, testEpsilon) {
+		t.Errorf("frame %d time %v", man.FrameIndex, man.Time)
+	}
+
+	platform.PushEvent(&sdl.QuitEvent{Type: sdl.QUIT})
+	if !man.Frame() {
+		t.Error("quit event didn't end the loop")
+	}
+}
+
+func TestManagerInputDispatch(t *testing.T) {
+	man, platform := newTestManager(t)
+	log := []string{}
+	a := newTestState("a", &log)
+	b := newTestState("b", &log)
+	man.Setup(a, "test")
+	man.Push(b)
+	log = nil
+
+	platform.PushEvent(keyEvent(sdl.K_a, true))
+	man.Frame()
+	checkLog(t, &log, "b key down 97")
+
+	b.inputBelow = true
+	platform.PushEvent(keyEvent(sdl.K_a, false))
+	man.Frame()
+	checkLog(t, &log, "b key up 97", "a key up 97")
+}
+
+func TestManagerDeferredStackOps(t *testing.T) {
+	man, platform := newTestManager(t)
+	log := []string{}
+	a := newTestState("a", &log)
+	b := newTestState("b", &log)
+	c := newTestState("c", &log)
+	man.Setup(a, "test")
+	log = nil
+
+	a.process = func(time_step float32) {
+		man.Push(b)
+		man.Push(c)
+		if man.Pop() != nil {
+			t.Error("Pop during frame returned state")
+		}
+		if man.GetRunningState() != AppState(a) {
+			t.Error("stack changed during frame")
+		}
+	}
+	platform.Advance(16)
+	man.Frame()
+	a.process = nil
+
+	checkLog(t, &log, "a pause", "b setup", "b pause", "c setup", "c destroy", "b resume")
+	if names := stackNames(man); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("stack %v", names)
+	}
+
+	// Outside of frame changes are immediate
+	if man.Pop() != AppState(b) {
+		t.Error("Pop didn't return running state")
+	}
+	checkLog(t, &log, "b destroy", "a resume")
+}
+
+// Transition logging its calls, doesn't draw
+type testTransition struct {
+	duration, switchPoint float32
+	log                   *[]string
+}
+
+func (self *testTransition) Duration() float32          { return self.duration }
+func (self *testTransition) SwitchPoint() float32       { return self.switchPoint }
+func (self *testTransition) Begin(man *AppStateManager) { *self.log = append(*self.log, "begin") }
+func (self *testTransition) Render(progress float32)    {}
+func (self *testTransition) End()                       { *self.log = append(*self.log, "end") }
+
+func TestManagerTransition(t *testing.T) {
+	man, platform := newTestManager(t)
+	log := []string{}
+	a := newTestState("a", &log)
+	b := newTestState("b", &log)
+	man.Setup(a, "test")
+	log = nil
+
+	trans := &testTransition{0.1, 0.5, &log}
+	man.PushWith(b, trans, func() { log = append(log, "done") })
+	checkLog(t, &log, "begin")
+
+	platform.Advance(40)
+	man.Frame()
+	checkLog(t, &log)
+	if !man.InTransition() || man.GetRunningState() != AppState(a) {
+		t.Error("switched before switch point")
+	}
+
+	platform.Advance(20)
+	man.Frame()
+	checkLog(t, &log, "a pause", "b setup")
+
+	platform.Advance(50)
+	man.Frame()
+	checkLog(t, &log, "end", "done")
+	if man.InTransition() {
+		t.Error("transition still running")
+	}
+}
+
+func TestManagerReplay(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "input.rec")
+	frames := [][]sdl.Event{
+		{keyEvent(sdl.K_a, true)},
+		{&sdl.MouseMotionEvent{Type: sdl.MOUSEMOTION, X: 10, Y: 20},
+			&sdl.MouseMotionEvent{Type: sdl.MOUSEMOTION, X: 13, Y: 18}},
+		{keyEvent(sdl.K_a, false),
+			&sdl.MouseButtonEvent{Type: sdl.MOUSEBUTTONDOWN, Button: 1, State: 1, X: 13, Y: 18}},
+	}
+
+	run := func(replay bool) (*testState, []string) {
+		man, platform := newTestManager(t)
+		log := []string{}
+		a := newTestState("a", &log)
+		man.Setup(a, "test")
+		log = nil
+
+		if replay {
+			if err := man.StartReplay(filename); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := man.StartRecording(filename); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, events := range frames {
+			if !replay {
+				platform.PushEvent(events...)
+			}
+			platform.Advance(uint32(10 + i))
+			man.Frame()
+		}
+		if err := man.StopRecording(); err != nil {
+			t.Fatal(err)
+		}
+		return a, log
+	}
+
+	recorded, recordedLog := run(false)
+	replayed, replayedLog := run(true)
+
+	if len(recordedLog) != 5 {
+		t.Fatalf("recorded %q", recordedLog)
+	}
+
+	if !reflect.DeepEqual(replayedLog, recordedLog) {
+		t.Errorf("replayed\n%q\nrecorded\n%q", replayedLog, recordedLog)
+	}
+	if !reflect.DeepEqual(replayed.steps, recorded.steps) {
+		t.Errorf("replayed steps %v, recorded %v", replayed.steps, recorded.steps)
+	}
+}
+
+func TestManagerFixedStep(t *testing.T) {
+	man, platform := newTestManager(t)
+	log := []string{}
+	a := newRenderTestState("a", &log)
+	man.Setup(a, "test")
+	man.SetFixedStep(100, 3)
+
+	cases := []struct {
+		name  string
+		ms    uint32
+		steps int
+		alpha float32
+	}{
+		{"two steps", 25, 2, 0.5},
+		{"no step", 3, 0, 0.8},
+		{"leftover catches up", 4, 1, 0.2},
+		{"spiral of death clamp", 55, 3, 0.7},
+		{"after clamp", 2, 0, 0.9},
+	}
+
+	for _, c := range cases {
+		a.steps, a.alphas = nil, nil
+		platform.Advance(c.ms)
+		man.Frame()
+
+		if len(a.steps) != c.steps {
+			t.Errorf("%s: %d steps, want %d", c.name, len(a.steps), c.steps)
+		}
+		for _, step := range a.steps {
+			if step != man.StepTime {
+				t.Errorf("%s: step %v, want %v", c.name, step, man.StepTime)
+			}
+		}
+		if len(a.alphas) != 1 || !nearlyEqual(a.alphas[0], c.alpha, 1e-3) {
+			t.Errorf("%s: rendered with %v, want alpha %v", c.name, a.alphas, c.alpha)
+		}
+	}
+}
+
+func TestManagerRenderMixedStates(t *testing.T) {
+	log := []string{}
+	legacy := func() AppState { return newTestState("legacy", &log) }
+	rendering := func() AppState { return newRenderTestState("rendering", &log) }
+
+	cases := []struct {
+		name   string
+		states []AppState
+		opaque bool
+		frames int
+	}{
+		{"rendering", []AppState{rendering()}, true, 1},
+		{"legacy", []AppState{legacy()}, true, 0},
+		{"rendering over rendering", []AppState{rendering(), rendering()}, false, 1},
+		{"rendering over legacy", []AppState{legacy(), rendering()}, false, 0},
+		{"legacy over rendering", []AppState{rendering(), legacy()}, false, 0},
+		// Legacy state isn't visible beneath opaque one
+		{"opaque rendering over legacy", []AppState{legacy(), rendering()}, true, 1},
+	}
+
+	for _, c := range cases {
+		man, platform := newTestManager(t)
+		man.Setup(c.states[0], "test")
+		for _, state := range c.states[1:] {
+			man.Push(state)
+		}
+		switch top := c.states[len(c.states)-1].(type) {
+		case *testState:
+			top.renderBelow = !c.opaque
+		case *renderTestState:
+			top.renderBelow = !c.opaque
+		}
+
+		platform.Advance(16)
+		man.Frame()
+		if platform.Clears != c.frames || platform.Swaps != c.frames {
+			t.Errorf("%s: %d clears %d swaps, want %d", c.name, platform.Clears, platform.Swaps, c.frames)
+		}
+	}
+}
+
+func TestManagerFixedStepInputEdges(t *testing.T) {
+	man, platform := newTestManager(t)
+	log := []string{}
+	a := newTestState("a", &log)
+	man.Setup(a, "test")
+	man.SetFixedStep(60, 5)
+	man.Input.BindKey("jump", sdl.K_SPACE, 0)
+
+	pressed, released := 0, 0
+	a.process = func(time_step float32) {
+		if man.Input.Pressed("jump") {
+			pressed++
+		}
+		if man.Input.Released("jump") {
+			released++
+		}
+	}
+
+	// Tap shorter than a step, in frames too short to run one
+	platform.PushEvent(keyEvent(sdl.K_SPACE, true), keyEvent(sdl.K_SPACE, false))
+	for i := 0; i < 10; i++ {
+		platform.Advance(4)
+		man.Frame()
+	}
+	if len(a.steps) == 0 || pressed != 1 || released != 1 {
+		t.Errorf("short frames: %d steps saw %d presses %d releases, want 1 each",
+			len(a.steps), pressed, released)
+	}
+
+	// Catch-up frame runs several steps, only first sees the edge
+	pressed, released = 0, 0
+	a.steps = nil
+	platform.PushEvent(keyEvent(sdl.K_SPACE, true))
+	platform.Advance(50)
+	man.Frame()
+	if len(a.steps) < 2 || pressed != 1 {
+		t.Errorf("long frame: %d steps saw %d presses, want 1", len(a.steps), pressed)
+	}
+	if !man.Input.Held("jump") || man.Input.Pressed("jump") {
+		t.Error("press not consumed or key not held")
+	}
+}
+
+func TestManagerTimersUnderOverlay(t *testing.T) {
+	man, platform := newTestManager(t)
+	log := []string{}
+	game := newTestState("game", &log)
+	console := newTestState("console", &log)
+	console.updateBelow = true
+	menu := newTestState("menu", &log)
+	man.Setup(game, "test")
+
+	ticks := 0
+	man.Scheduler.Every(game, 0.01, func() { ticks++ })
+	frame := func() int {
+		ticks = 0
+		platform.Advance(25)
+		man.Frame()
+		return ticks
+	}
+
+	man.Push(console)
+	if got := frame(); got != 2 {
+		t.Errorf("under console: %d ticks, want 2", got)
+	}
+
+	man.Push(menu)
+	if got := frame(); got != 0 {
+		t.Errorf("under menu: %d ticks, want 0", got)
+	}
+
+	man.Pop()
+	if got := frame(); got != 3 {
+		t.Errorf("menu popped: %d ticks, want 3", got)
+	}
+
+	man.Replace(menu)
+	if got := frame(); got != 0 {
+		t.Errorf("console replaced with menu: %d ticks, want 0", got)
+	}
+}
+
+// Stands in for GL object wrapper
+type fakeResource struct {
+	destroyed bool
+}
+
+func (self *fakeResource) Destroy() {
+	self.destroyed = true
+	GetResourceRegistry().untrack(self)
+}
+
+func TestManagerResourceOwner(t *testing.T) {
+	man, platform := newTestManager(t)
+	man.FreeStateResources = true
+	log := []string{}
+	game := newTestState("game", &log)
+	console := newTestState("console", &log)
+	console.updateBelow = true
+	man.Setup(game, "test")
+	man.Push(console)
+
+	registry := GetResourceRegistry()
+	shared, mesh := &fakeResource{}, &fakeResource{}
+	registry.track(shared, RESOURCE_BUFFER, "shared", 0)
+	defer shared.Destroy()
+	game.process = func(time_step float32) {
+		if registry.Resources[mesh] == nil {
+			registry.track(mesh, RESOURCE_BUFFER, "mesh", 0)
+		}
+	}
+	platform.Advance(16)
+	man.Frame()
+
+	if owner := registry.Resources[shared].Owner; owner != nil {
+		t.Errorf("object created outside of states owned by %v", owner)
+	}
+	if res := registry.Resources[mesh]; res == nil || res.Owner != AppState(game) {
+		t.Fatalf("mesh created by game under console: %+v", res)
+	}
+
+	man.Pop()
+	if mesh.destroyed {
+		t.Error("mesh of game freed with console")
+	}
+	man.Pop()
+	if !mesh.destroyed {
+		t.Error("mesh not freed with game")
+	}
+}
@@ -0,0 +1,234 @@
+package glutils
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Asset that couldn't be loaded
+type AssetFailure struct {
+	Name string
+	Err  error
+}
+
+// Turns file contents into mesh with CPU side arrays filled. Runs on
+// worker goroutine, so it must not call GL.
+type MeshParser func(data []byte) (*MeshBuffer, error)
+
+// Part of loading done on worker, then finished on the main thread
+type assetJob interface {
+	name() string
+	decode() error
+	upload(loader *AssetLoader) error
+}
+
+// Loads textures, shader programs and meshes in the background. Files
+// are read and decoded by worker goroutines, GL objects are created on
+// the main thread by Update, which is meant to be called every frame,
+// for example from loading screen Process.
+type AssetLoader struct {
+	// Seconds per Update spent on GL uploads, at least one asset is
+	// uploaded each time
+	Budget float32
+	// Assets that failed, main thread only
+	Failures []AssetFailure
+	// Loaded meshes by filename
+	Meshes map[string]*MeshBuffer
+
+	total    int
+	finished int
+
+	queued  map[string]bool
+	workers chan bool
+	lock    sync.Mutex
+	decoded []decodedAsset
+}
+
+type decodedAsset struct {
+	job assetJob
+	err error
+}
+
+func NewAssetLoader(workers int) *AssetLoader {
+	if workers < 1 {
+		workers = 1
+	}
+	return &AssetLoader{
+		Budget:  0.005,
+		Meshes:  map[string]*MeshBuffer{},
+		queued:  map[string]bool{},
+		workers: make(chan bool, workers)}
+}
+
+// Queue PNG texture, it's stored in TextureManager once loaded
+func (self *AssetLoader) LoadTexture(filename string, setup TexSetup) {
+	if GetTextureManager().Textures[filename] != nil {
+		return
+	}
+	self.start(&textureJob{filename: filename, setup: setup})
+}
+
+// Queue shader program, it's stored in ShaderManager once linked
+func (self *AssetLoader) LoadProgram(vertexFilename, fragFilename string) {
+	if GetShaderManager().Programs[vertexFilename+"|"+fragFilename] != nil {
+		return
+	}
+	self.start(&programJob{vertexFilename: vertexFilename, fragFilename: fragFilename})
+}
+
+// Queue mesh, its arrays are copied to VBO once parsed
+func (self *AssetLoader) LoadMesh(filename string, parse MeshParser) {
+	if self.Meshes[filename] != nil {
+		return
+	}
+	self.start(&meshJob{filename: filename, parse: parse})
+}
+
+func (self *AssetLoader) start(job assetJob) {
+	if self.queued[job.name()] {
+		return
+	}
+	self.queued[job.name()] = true
+	self.total++
+
+	go func() {
+		self.workers <- true
+		err := job.decode()
+		<-self.workers
+
+		self.lock.Lock()
+		self.decoded = append(self.decoded, decodedAsset{job, err})
+		self.lock.Unlock()
+	}()
+}
+
+// Upload decoded assets until the time budget is used up. Must be
+// called on the main thread.
+func (self *AssetLoader) Update() {
+	start := time.Now()
+	budget := time.Duration(self.Budget * float32(time.Second))
+
+	for {
+		self.lock.Lock()
+		if len(self.decoded) == 0 {
+			self.lock.Unlock()
+			return
+		}
+		asset := self.decoded[0]
+		self.decoded = self.decoded[1:]
+		self.lock.Unlock()
+
+		err := asset.err
+		if err == nil {
+			err = asset.job.upload(self)
+		}
+		if err != nil {
+			self.Failures = append(self.Failures, AssetFailure{asset.job.name(), err})
+		}
+		delete(self.queued, asset.job.name())
+		self.finished++
+
+		if time.Since(start) >= budget {
+			return
+		}
+	}
+}
+
+// Fraction of queued assets that are done, failed ones included
+func (self *AssetLoader) Progress() float32 {
+	if self.total == 0 {
+		return 1
+	}
+	return float32(self.finished) / float32(self.total)
+}
+
+func (self *AssetLoader) Done() bool {
+	return self.finished == self.total
+}
+
+type textureJob struct {
+	filename      string
+	setup         TexSetup
+	data          []byte
+	width, height int
+}
+
+func (self *textureJob) name() string {
+	return self.filename
+}
+
+func (self *textureJob) decode() (err error) {
+	data, err := GetVFS().ReadFile(self.filename)
+	if err != nil {
+		return err
+	}
+	self.data, self.width, self.height, err = decodeTexture(bytes.NewReader(data))
+	return err
+}
+
+func (self *textureJob) upload(loader *AssetLoader) error {
+	tm := GetTextureManager()
+	// Could have been loaded synchronously meanwhile
+	if tm.Textures[self.filename] == nil {
+		texture := uploadTexture(self.filename,
+			self.data, self.width, self.height, self.setup)
+		GetResourceRegistry().SetOwner(texture, nil)
+		tm.Textures[self.filename] = texture
+	}
+	return nil
+}
+
+type programJob struct {
+	vertexFilename, fragFilename string
+	vertexSource, fragSource     string
+}
+
+func (self *programJob) name() string {
+	return self.vertexFilename + "|" + self.fragFilename
+}
+
+func (self *programJob) decode() (err error) {
+	self.vertexSource, err = readShaderSource(self.vertexFilename)
+	if err != nil {
+		return err
+	}
+	self.fragSource, err = readShaderSource(self.fragFilename)
+	return err
+}
+
+func (self *programJob) upload(loader *AssetLoader) error {
+	_, err := GetShaderManager().programFromSources(
+		self.vertexFilename, self.vertexSource,
+		self.fragFilename, self.fragSource)
+	return err
+}
+
+type meshJob struct {
+	filename string
+	parse    MeshParser
+	mesh     *MeshBuffer
+}
+
+func (self *meshJob) name() string {
+	return self.filename
+}
+
+func (self *meshJob) decode() (err error) {
+	data, err := GetVFS().ReadFile(self.filename)
+	if err != nil {
+		return err
+	}
+	self.mesh, err = self.parse(data)
+	return err
+}
+
+func (self *meshJob) upload(loader *AssetLoader) error {
+	self.mesh.CopyArraysToVBO()
+	if res := GetResourceRegistry().Resources[self.mesh]; res != nil {
+		res.Name = self.filename
+		res.Owner = nil
+	}
+	loader.Meshes[self.filename] = self.mesh
+	return nil
+}
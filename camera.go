@@ -2,7 +2,6 @@ package glutils
 
 import (
 	"github.com/pzsz/gl"
-	"github.com/pzsz/glu"
 	v "github.com/pzsz/lin3dmath"
 	"math"
 
@@ -144,54 +143,97 @@ func (self *Camera) SetOrthoProjection(nearz, farz float32) {
 		nearz, farz)
 }
 
+// Vector from point under cursor towards the eye, not normalised. The
+// point is unprojected with window depth NearZ, as it always was. Zero
+// when matrices can't be inverted. Kept for old code, ScreenToRay gives
+// proper ray.
 func (self *Camera) GetViewRay(x, y float32) v.Vector3f {
-	viewport := [4]int32{0, 0, int32(self.Viewport.Width), int32(self.Viewport.Height)}
+	point, ok := self.Unproject(x, y, self.NearZ)
+	if !ok {
+		return v.Vector3f{}
+	}
+	return vecSub(self.EyePos, point)
+}
+
+// Window position, with y going down, and depth in 0..1 range from
+// near to far plane cast to world coordinates. Returns false when
+// matrices can't be inverted.
+func (self *Camera) Unproject(x, y, depth float32) (v.Vector3f, bool) {
+	combined := matrixMul(&self.ProjectionMatrix, &self.ModelviewMatrix)
+	inverse, ok := matrixInvert(&combined)
+	if !ok {
+		return v.Vector3f{}, false
+	}
 
-	mx, my, mz := glu.UnProject(
-		float64(x), float64(self.Viewport.Height)-float64(y), float64(self.NearZ),
-		self.ModelviewMatrix.ToArray64(),
-		self.ProjectionMatrix.ToArray64(), &viewport)
+	nx := 2*x/self.Viewport.Width - 1
+	ny := 1 - 2*y/self.Viewport.Height
+	nz := 2*depth - 1
 
-	return v.Vector3f{self.EyePos.X - float32(mx),
-		self.EyePos.Y - float32(my),
-		self.EyePos.Z - float32(mz)}
+	wx, wy, wz, ww := matrixTransform(&inverse, nx, ny, nz, 1)
+	if ww == 0 {
+		return v.Vector3f{}, false
+	}
+	return v.Vector3f{wx / ww, wy / ww, wz / ww}, true
 }
 
-// Cast viewport position to world coordinates placed on sphere of 
-// given radius
-func (self *Camera) ScreenToSphere(x, y, radius float32) v.Vector3f {
-	viewRay := self.GetViewRay(x, y)
+// Ray from near plane through window position, with y going down.
+// Works the same for perspective and ortho projection. Returns false
+// when matrices can't be inverted.
+func (self *Camera) ScreenToRay(x, y float32) (Ray, bool) {
+	near, ok := self.Unproject(x, y, 0)
+	if !ok {
+		return Ray{}, false
+	}
+	far, ok := self.Unproject(x, y, 1)
+	if !ok {
+		return Ray{}, false
+	}
+	return NewRay(near, vecSub(far, near)), true
+}
 
-	if viewRay.Z == 0 {
-		return v.Vector3f{}
+// Window position, with y going down, and depth in 0..1 range of world
+// point. Returns false for points behind the eye.
+func (self *Camera) WorldToScreen(point v.Vector3f) (x, y, depth float32, ok bool) {
+	combined := matrixMul(&self.ProjectionMatrix, &self.ModelviewMatrix)
+	cx, cy, cz, cw := matrixTransform(&combined, point.X, point.Y, point.Z, 1)
+	if cw <= 0 {
+		return 0, 0, 0, false
 	}
 
-	viewRay.NormalizeIP()
-	viewRay.MulIP(radius)
-	return self.EyePos.Sub(viewRay)
+	x = (cx/cw + 1) / 2 * self.Viewport.Width
+	y = (1 - cy/cw) / 2 * self.Viewport.Height
+	depth = (cz/cw + 1) / 2
+	return x, y, depth, true
 }
 
+// Cast viewport position to world coordinates placed on sphere of 
+// given radius, EyePos when matrices can't be inverted
+func (self *Camera) ScreenToSphere(x, y, radius float32) v.Vector3f {
+	ray, _ := self.ScreenToRay(x, y)
+	return vecAdd(self.EyePos, vecScale(ray.Direction, radius))
+}
 
+// Point of XY plane at height z under window position, zero when view
+// is parallel to the plane or matrices can't be inverted
 func (self *Camera) ScreenToPlaneXY(x, y, z float32) v.Vector2f {
-	viewRay := self.GetViewRay(x, y)
+	ray, ok := self.ScreenToRay(x, y)
 
-	if viewRay.Z == 0 {
+	if !ok || ray.Direction.Z == 0 {
 		return v.Vector2f{0, 0}
 	}
 
-	t := (z - self.EyePos.Z) / viewRay.Z
-	retx := self.EyePos.X + t*viewRay.X
-	rety := self.EyePos.Y + t*viewRay.Y
-
-	return v.Vector2f{retx, rety}
+	hit := ray.At((z - ray.Origin.Z) / ray.Direction.Z)
+	return v.Vector2f{hit.X, hit.Y}
 }
 
 func (self *Camera) LoadProjection() {
+	defer checkGL("Camera.LoadProjection")
 	gl.MatrixMode(gl.PROJECTION)
 	gl.LoadMatrixf(self.ProjectionMatrix.ToArray32())
 }
 
 func (self *Camera) LoadModelview(m *v.Matrix4) {
+	defer checkGL("Camera.LoadModelview")
 	gl.MatrixMode(gl.MODELVIEW)
 
 	fu := self.ModelviewMatrix.Mul(m)
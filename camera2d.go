@@ -0,0 +1,154 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+)
+
+// Camera for 2D scenes looking at Position, with world y axis going up.
+// SetupCamera fills matrices of Camera, which is then passed to render
+// ops as usual.
+type Camera2D struct {
+	Camera *Camera
+
+	// World point in the middle of the screen
+	Position v.Vector2f
+	// Screen pixels per world unit
+	Zoom             float32
+	MinZoom, MaxZoom float32
+	// Counter clockwise, in radians
+	Rotation float32
+
+	// Visible area is kept inside bounds when ClampToBounds is set. When
+	// bounds are smaller than the screen they're centred.
+	ClampToBounds        bool
+	BoundsMin, BoundsMax v.Vector2f
+
+	// How fast Follow catches up with target, fraction of distance
+	// left after one second is exp(-FollowSpeed)
+	FollowSpeed float32
+}
+
+func NewCamera2D(camera *Camera) *Camera2D {
+	return &Camera2D{
+		Camera:      camera,
+		Zoom:        1,
+		MinZoom:     0.01,
+		MaxZoom:     100,
+		FollowSpeed: 5}
+}
+
+// Move by screen distance, as when dragging the map
+func (self *Camera2D) Pan(dx, dy float32) {
+	c, s := self.rotation()
+	wx, wy := dx/self.Zoom, -dy/self.Zoom
+	self.Position.X -= c*wx - s*wy
+	self.Position.Y -= s*wx + c*wy
+	self.clamp()
+}
+
+func (self *Camera2D) SetZoom(zoom float32) {
+	self.Zoom = float32(math.Max(float64(self.MinZoom),
+		math.Min(float64(self.MaxZoom), float64(zoom))))
+	self.clamp()
+}
+
+// Multiply zoom by factor, keeping world point under screen position
+// x,y in place
+func (self *Camera2D) ZoomAt(x, y, factor float32) {
+	before := self.ScreenToWorld(x, y)
+	self.Zoom = float32(math.Max(float64(self.MinZoom),
+		math.Min(float64(self.MaxZoom), float64(self.Zoom*factor))))
+	after := self.ScreenToWorld(x, y)
+
+	self.Position.X += before.X - after.X
+	self.Position.Y += before.Y - after.Y
+	self.clamp()
+}
+
+// Move smoothly towards target, to be called every frame
+func (self *Camera2D) Follow(target v.Vector2f, time_step float32) {
+	k := 1 - float32(math.Exp(float64(-self.FollowSpeed*time_step)))
+	self.Position.X += (target.X - self.Position.X) * k
+	self.Position.Y += (target.Y - self.Position.Y) * k
+	self.clamp()
+}
+
+// Screen position, with y going down, to world point
+func (self *Camera2D) ScreenToWorld(x, y float32) v.Vector2f {
+	vp := self.Camera.Viewport
+	vx := (x - vp.Width/2) / self.Zoom
+	vy := (vp.Height/2 - y) / self.Zoom
+
+	c, s := self.rotation()
+	return v.Vector2f{
+		self.Position.X + c*vx - s*vy,
+		self.Position.Y + s*vx + c*vy}
+}
+
+// World point to screen position, with y going down
+func (self *Camera2D) WorldToScreen(point v.Vector2f) (x, y float32) {
+	vp := self.Camera.Viewport
+	dx := point.X - self.Position.X
+	dy := point.Y - self.Position.Y
+
+	c, s := self.rotation()
+	vx := (c*dx + s*dy) * self.Zoom
+	vy := (-s*dx + c*dy) * self.Zoom
+	return vp.Width/2 + vx, vp.Height/2 - vy
+}
+
+// Set ortho projection centred on the screen and view transform of
+// Camera
+func (self *Camera2D) SetupCamera() {
+	vp := self.Camera.Viewport
+	self.Camera.Fov = 0
+	self.Camera.NearZ = -1
+	self.Camera.FarZ = 1
+	self.Camera.ProjectionMatrix = *CreateOrthoMatrix(-vp.Width/2, vp.Width/2,
+		-vp.Height/2, vp.Height/2, -1, 1)
+
+	c, s := self.rotation()
+	z := self.Zoom
+	x, y := self.Position.X, self.Position.Y
+	self.Camera.ModelviewMatrix = v.Matrix4{
+		z * c, -z * s, 0, 0,
+		z * s, z * c, 0, 0,
+		0, 0, 1, 0,
+		-z * (c*x + s*y), z * (s*x - c*y), 0, 1}
+	self.Camera.EyePos = v.Vector3f{x, y, 0}
+	self.Camera.ViewPos = v.Vector3f{x, y, -1}
+}
+
+func (self *Camera2D) rotation() (c, s float32) {
+	return float32(math.Cos(float64(self.Rotation))), float32(math.Sin(float64(self.Rotation)))
+}
+
+func (self *Camera2D) clamp() {
+	if !self.ClampToBounds {
+		return
+	}
+
+	// Half size of visible area, as box aligned with world axes
+	vp := self.Camera.Viewport
+	c, s := self.rotation()
+	c, s = float32(math.Abs(float64(c))), float32(math.Abs(float64(s)))
+	halfW := (vp.Width/2*c + vp.Height/2*s) / self.Zoom
+	halfH := (vp.Width/2*s + vp.Height/2*c) / self.Zoom
+
+	self.Position.X = clampAxis(self.Position.X, self.BoundsMin.X, self.BoundsMax.X, halfW)
+	self.Position.Y = clampAxis(self.Position.Y, self.BoundsMin.Y, self.BoundsMax.Y, halfH)
+}
+
+func clampAxis(pos, min, max, half float32) float32 {
+	if max-min < 2*half {
+		return (min + max) / 2
+	}
+	if pos < min+half {
+		return min + half
+	}
+	if pos > max-half {
+		return max - half
+	}
+	return pos
+}
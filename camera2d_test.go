@@ -0,0 +1,100 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+	"testing"
+)
+
+func vec2NearlyEqual(a, b v.Vector2f, epsilon float32) bool {
+	return nearlyEqual(a.X, b.X, epsilon) && nearlyEqual(a.Y, b.Y, epsilon)
+}
+
+type testCamera2D struct {
+	name string
+	cam  *Camera2D
+}
+
+func testCameras2D() []testCamera2D {
+	newCam := func(x, y, zoom, rotation float32) *Camera2D {
+		cam := NewCamera2D(NewCamera(newTestViewport()))
+		cam.Position = v.Vector2f{x, y}
+		cam.Zoom = zoom
+		cam.Rotation = rotation
+		return cam
+	}
+
+	return []testCamera2D{
+		{"default", newCam(0, 0, 1, 0)},
+		{"moved and zoomed", newCam(100, 50, 2, 0)},
+		{"zoomed out", newCam(-30, 7, 0.25, 0)},
+		{"rotated", newCam(100, 50, 2, math.Pi/2)},
+		{"rotated odd angle", newCam(-5, 12, 1.5, 0.3)},
+	}
+}
+
+func TestCamera2DScreenToWorld(t *testing.T) {
+	cams := testCameras2D()
+	moved, rotated := cams[1].cam, cams[3].cam
+
+	cases := []struct {
+		name string
+		cam  *Camera2D
+		x, y float32
+		want v.Vector2f
+	}{
+		{"centre", moved, 400, 300, v.Vector2f{100, 50}},
+		{"top left", moved, 0, 0, v.Vector2f{-100, 200}},
+		{"bottom right", moved, 800, 600, v.Vector2f{300, -100}},
+		// Screen right is world up after quarter turn
+		{"rotated right edge", rotated, 800, 300, v.Vector2f{100, 250}},
+		{"rotated top edge", rotated, 400, 0, v.Vector2f{-50, 50}},
+	}
+
+	for _, c := range cases {
+		if got := c.cam.ScreenToWorld(c.x, c.y); !vec2NearlyEqual(got, c.want, testEpsilon) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCamera2DRoundTrip(t *testing.T) {
+	for _, tc := range testCameras2D() {
+		tc.cam.SetupCamera()
+		for _, p := range testScreenPoints {
+			world := tc.cam.ScreenToWorld(p.x, p.y)
+
+			// Within hundredth of a pixel
+			x, y := tc.cam.WorldToScreen(world)
+			if math.Abs(float64(x-p.x)) > 0.01 || math.Abs(float64(y-p.y)) > 0.01 {
+				t.Errorf("%s %s: projected back to %v,%v", tc.name, p.name, x, y)
+			}
+
+			// Matrices of SetupCamera agree
+			unprojected, ok := tc.cam.Camera.Unproject(p.x, p.y, 0.5)
+			if !ok || !vec2NearlyEqual(v.Vector2f{unprojected.X, unprojected.Y}, world, 1e-3) {
+				t.Errorf("%s %s: camera unprojects to %v ok=%v, want %v", tc.name, p.name, unprojected, ok, world)
+			}
+		}
+	}
+}
+
+func TestCamera2DZoomAt(t *testing.T) {
+	for _, tc := range testCameras2D() {
+		for _, p := range testScreenPoints {
+			for _, factor := range []float32{2, 0.5, 1e6} {
+				cam := *tc.cam
+				before := cam.ScreenToWorld(p.x, p.y)
+				cam.ZoomAt(p.x, p.y, factor)
+
+				wantZoom := float32(math.Min(float64(tc.cam.Zoom*factor), float64(cam.MaxZoom)))
+				if !nearlyEqual(cam.Zoom, wantZoom, testEpsilon) {
+					t.Errorf("%s %s x%v: zoom %v, want %v", tc.name, p.name, factor, cam.Zoom, wantZoom)
+				}
+				if after := cam.ScreenToWorld(p.x, p.y); !vec2NearlyEqual(after, before, 1e-3) {
+					t.Errorf("%s %s x%v: point under cursor moved from %v to %v", tc.name, p.name, factor, before, after)
+				}
+			}
+		}
+	}
+}
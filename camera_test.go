@@ -0,0 +1,263 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+	"testing"
+)
+
+type testCamera struct {
+	name string
+	cam  *Camera
+}
+
+func newTestViewport() *Viewport {
+	return &Viewport{Width: 800, Height: 600, Aspect: 800.0 / 600.0}
+}
+
+func testCameras() []testCamera {
+	frustum := NewCamera(newTestViewport())
+	frustum.SetFrustrumProjection(60, 1, 100)
+	frustum.ModelviewMatrix = identityMatrix
+
+	eye := v.Vector3f{5, 3, 8}
+	view := lookAtMatrix(eye, v.Vector3f{0, 0, 0}, v.Vector3f{0, 1, 0})
+	lookAt := NewCamera(newTestViewport())
+	lookAt.SetFrustrumProjection(45, 0.5, 50)
+	lookAt.SetCustomModelview(eye.X, eye.Y, eye.Z, &view)
+
+	ortho := NewCamera(newTestViewport())
+	ortho.SetOrthoProjection(-1, 1)
+	ortho.ModelviewMatrix = identityMatrix
+
+	orthoMoved := NewCamera(newTestViewport())
+	orthoMoved.SetOrthoProjection(-10, 10)
+	orthoMoved.ModelviewMatrix = translationMatrix(-100, -50, 0)
+
+	return []testCamera{
+		{"frustum", frustum},
+		{"frustum look at", lookAt},
+		{"ortho", ortho},
+		{"ortho moved", orthoMoved},
+	}
+}
+
+var testScreenPoints = []struct {
+	name string
+	x, y float32
+}{
+	{"centre", 400, 300},
+	{"top left", 0, 0},
+	{"top right", 800, 0},
+	{"bottom left", 0, 600},
+	{"bottom right", 800, 600},
+}
+
+func TestUnproject(t *testing.T) {
+	cams := testCameras()
+	frustum, ortho, orthoMoved := cams[0].cam, cams[2].cam, cams[3].cam
+	ymax := float32(math.Tan(math.Pi / 6))
+	xmax := ymax * 800 / 600
+
+	cases := []struct {
+		name        string
+		cam         *Camera
+		x, y, depth float32
+		want        v.Vector3f
+	}{
+		{"frustum centre near", frustum, 400, 300, 0, v.Vector3f{0, 0, -1}},
+		{"frustum centre far", frustum, 400, 300, 1, v.Vector3f{0, 0, -100}},
+		{"frustum top left near", frustum, 0, 0, 0, v.Vector3f{-xmax, ymax, -1}},
+		{"frustum top left far", frustum, 0, 0, 1, v.Vector3f{-100 * xmax, 100 * ymax, -100}},
+		{"frustum bottom right near", frustum, 800, 600, 0, v.Vector3f{xmax, -ymax, -1}},
+		{"frustum bottom right far", frustum, 800, 600, 1, v.Vector3f{100 * xmax, -100 * ymax, -100}},
+		{"ortho centre near", ortho, 400, 300, 0, v.Vector3f{400, 300, 1}},
+		{"ortho top left near", ortho, 0, 0, 0, v.Vector3f{0, 600, 1}},
+		{"ortho bottom right far", ortho, 800, 600, 1, v.Vector3f{800, 0, -1}},
+		{"ortho moved top left far", orthoMoved, 0, 0, 1, v.Vector3f{100, 650, -10}},
+		{"ortho moved bottom right near", orthoMoved, 800, 600, 0, v.Vector3f{900, 50, 10}},
+	}
+
+	for _, c := range cases {
+		got, ok := c.cam.Unproject(c.x, c.y, c.depth)
+		if !ok || !vecNearlyEqual(got, c.want, testEpsilon) {
+			t.Errorf("%s: got %v ok=%v, want %v", c.name, got, ok, c.want)
+		}
+	}
+}
+
+func TestUnprojectRoundTrip(t *testing.T) {
+	for _, tc := range testCameras() {
+		for _, p := range testScreenPoints {
+			for _, depth := range []float32{0, 1} {
+				world, ok := tc.cam.Unproject(p.x, p.y, depth)
+				if !ok {
+					t.Errorf("%s %s depth %v: unproject failed", tc.name, p.name, depth)
+					continue
+				}
+
+				// Within hundredth of a pixel
+				x, y, d, ok := tc.cam.WorldToScreen(world)
+				if !ok || math.Abs(float64(x-p.x)) > 0.01 || math.Abs(float64(y-p.y)) > 0.01 || !nearlyEqual(d, depth, 1e-3) {
+					t.Errorf("%s %s depth %v: projected back to %v,%v,%v ok=%v",
+						tc.name, p.name, depth, x, y, d, ok)
+				}
+			}
+		}
+	}
+}
+
+func TestWorldToScreenBehindEye(t *testing.T) {
+	cam := testCameras()[0].cam
+	if _, _, _, ok := cam.WorldToScreen(v.Vector3f{0, 0, 5}); ok {
+		t.Error("point behind the eye reported on screen")
+	}
+}
+
+func TestScreenToRay(t *testing.T) {
+	for _, tc := range testCameras() {
+		for _, p := range testScreenPoints {
+			ray, ok := tc.cam.ScreenToRay(p.x, p.y)
+			if !ok {
+				t.Errorf("%s %s: failed", tc.name, p.name)
+				continue
+			}
+
+			near, _ := tc.cam.Unproject(p.x, p.y, 0)
+			if !vecNearlyEqual(ray.Origin, near, testEpsilon) {
+				t.Errorf("%s %s: origin %v, want %v", tc.name, p.name, ray.Origin, near)
+			}
+			if !nearlyEqual(vecLength(ray.Direction), 1, testEpsilon) {
+				t.Errorf("%s %s: direction %v not normalised", tc.name, p.name, ray.Direction)
+			}
+		}
+	}
+}
+
+func TestScreenToRaySingular(t *testing.T) {
+	cam := NewCamera(newTestViewport())
+	cam.EyePos = v.Vector3f{1, 2, 3}
+	cam.ModelviewMatrix = identityMatrix
+
+	if ray, ok := cam.ScreenToRay(400, 300); ok || ray != (Ray{}) {
+		t.Errorf("ScreenToRay = %v ok=%v, want zero ray and false", ray, ok)
+	}
+	if _, ok := cam.Unproject(400, 300, 0); ok {
+		t.Error("Unproject succeeded with zero projection")
+	}
+	if got := cam.ScreenToPlaneXY(400, 300, 0); got != (v.Vector2f{0, 0}) {
+		t.Errorf("ScreenToPlaneXY = %v, want zero", got)
+	}
+	if got := cam.ScreenToSphere(400, 300, 10); got != cam.EyePos {
+		t.Errorf("ScreenToSphere = %v, want EyePos", got)
+	}
+}
+
+// Reference gluUnProject, computed in float64 with Gauss-Jordan
+// elimination. Window y goes up.
+func referenceUnproject(cam *Camera, winx, winy, winz float32) v.Vector3f {
+	var m [4][4]float64
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			for i := 0; i < 4; i++ {
+				m[row][col] += float64(cam.ProjectionMatrix[i*4+row]) * float64(cam.ModelviewMatrix[col*4+i])
+			}
+		}
+	}
+
+	in := [4]float64{
+		2*float64(winx)/float64(cam.Viewport.Width) - 1,
+		2*float64(winy)/float64(cam.Viewport.Height) - 1,
+		2*float64(winz) - 1,
+		1}
+
+	// Solve m*out = in
+	for col := 0; col < 4; col++ {
+		pivot := col
+		for row := col + 1; row < 4; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		in[col], in[pivot] = in[pivot], in[col]
+
+		for row := 0; row < 4; row++ {
+			if row == col {
+				continue
+			}
+			k := m[row][col] / m[col][col]
+			for i := col; i < 4; i++ {
+				m[row][i] -= k * m[col][i]
+			}
+			in[row] -= k * in[col]
+		}
+	}
+
+	var out [4]float64
+	for i := range out {
+		out[i] = in[i] / m[i][i]
+	}
+	return v.Vector3f{float32(out[0] / out[3]), float32(out[1] / out[3]), float32(out[2] / out[3])}
+}
+
+// View ray as GetViewRay computed it with glu.UnProject
+func referenceViewRay(cam *Camera, x, y float32) v.Vector3f {
+	return vecSub(cam.EyePos, referenceUnproject(cam, x, cam.Viewport.Height-y, cam.NearZ))
+}
+
+func TestGetViewRay(t *testing.T) {
+	for _, tc := range testCameras() {
+		for _, p := range testScreenPoints {
+			want := referenceViewRay(tc.cam, p.x, p.y)
+			if got := tc.cam.GetViewRay(p.x, p.y); !vecNearlyEqual(got, want, 1e-3) {
+				t.Errorf("%s %s: got %v, want %v", tc.name, p.name, got, want)
+			}
+		}
+	}
+}
+
+func TestScreenToPlaneXY(t *testing.T) {
+	for _, tc := range testCameras()[:2] {
+		for _, p := range testScreenPoints {
+			for _, z := range []float32{0, -3} {
+				eye := tc.cam.EyePos
+				ray := referenceViewRay(tc.cam, p.x, p.y)
+				want := vecAdd(eye, vecScale(ray, (z-eye.Z)/ray.Z))
+
+				got := tc.cam.ScreenToPlaneXY(p.x, p.y, z)
+				if !nearlyEqual(got.X, want.X, 1e-3) || !nearlyEqual(got.Y, want.Y, 1e-3) {
+					t.Errorf("%s %s z=%v: got %v, want %v", tc.name, p.name, z, got, want)
+				}
+			}
+		}
+	}
+
+	// Ortho camera looks straight down Z, so plane point is the window
+	// position itself
+	for _, tc := range testCameras()[2:] {
+		for _, p := range testScreenPoints {
+			want, _ := tc.cam.Unproject(p.x, p.y, 0.5)
+			got := tc.cam.ScreenToPlaneXY(p.x, p.y, 0)
+			if !nearlyEqual(got.X, want.X, testEpsilon) || !nearlyEqual(got.Y, want.Y, testEpsilon) {
+				t.Errorf("%s %s: got %v, want %v", tc.name, p.name, got, want)
+			}
+		}
+	}
+}
+
+func TestScreenToSphere(t *testing.T) {
+	for _, tc := range testCameras()[:2] {
+		for _, p := range testScreenPoints {
+			for _, radius := range []float32{1, 25} {
+				ray := vecNormalize(referenceViewRay(tc.cam, p.x, p.y))
+				want := vecSub(tc.cam.EyePos, vecScale(ray, radius))
+
+				got := tc.cam.ScreenToSphere(p.x, p.y, radius)
+				if !vecNearlyEqual(got, want, 1e-3) {
+					t.Errorf("%s %s radius %v: got %v, want %v", tc.name, p.name, radius, got, want)
+				}
+			}
+		}
+	}
+}
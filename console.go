@@ -0,0 +1,376 @@
+package glutils
+
+import (
+	"fmt"
+	"github.com/banthar/Go-SDL/sdl"
+	"github.com/pzsz/gl"
+	v "github.com/pzsz/lin3dmath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const CONSOLE_MAX_LINES = 512
+const CONSOLE_MAX_HISTORY = 64
+
+// Overlay state with scrollback and command line, running commands
+// from ConsoleRegistry. Game beneath keeps running and drawing.
+type ConsoleState struct {
+	Registry *ConsoleRegistry
+	// Text isn't drawn without font
+	Font       *BitmapFont
+	Background Colour
+	TextColour Colour
+
+	Lines   []string
+	History []string
+
+	man     *AppStateManager
+	input   []rune
+	cursor  int
+	scroll  int
+	histPos int
+	// Text input mode of state beneath, restored on close
+	textInputWas bool
+}
+
+// Create console and register built-in commands in global registry
+func NewConsoleState(man *AppStateManager, font *BitmapFont) *ConsoleState {
+	ret := &ConsoleState{
+		Registry:   GetConsoleRegistry(),
+		Font:       font,
+		Background: Colour{0, 0, 0, 192},
+		TextColour: Colour{255, 255, 255, 255},
+		man:        man}
+	ret.registerBuiltins()
+	return ret
+}
+
+// Show console when it's not on the stack, hide it when it's on top.
+// Does nothing when console is already being shown or hidden this frame.
+func (self *AppStateManager) ToggleConsole() {
+	if self.Console == nil {
+		return
+	}
+
+	console := AppState(self.Console)
+	for _, op := range self.pending {
+		if op.state == console || (op.kind == opPop && self.GetRunningState() == console) {
+			return
+		}
+	}
+
+	if self.GetRunningState() == console {
+		self.Pop()
+		return
+	}
+
+	for e := self.StateStack.Front(); e != nil; e = e.Next() {
+		if e.Value.(AppState) == console {
+			return
+		}
+	}
+	self.Push(self.Console)
+}
+
+// Add text to scrollback, may hold several lines
+func (self *ConsoleState) Print(text string) {
+	for _, line := range strings.Split(text, "\n") {
+		self.Lines = append(self.Lines, line)
+	}
+	if len(self.Lines) > CONSOLE_MAX_LINES {
+		self.Lines = self.Lines[len(self.Lines)-CONSOLE_MAX_LINES:]
+	}
+	self.scroll = 0
+}
+
+func (self *ConsoleState) Printf(format string, args ...interface{}) {
+	self.Print(fmt.Sprintf(format, args...))
+}
+
+// Run command line as if it was typed in
+func (self *ConsoleState) Execute(line string) {
+	self.Print("> " + line)
+
+	out, err := self.Registry.Execute(line)
+	if err != nil {
+		self.Print("Error: " + err.Error())
+	} else if out != "" {
+		self.Print(out)
+	}
+}
+
+func (self *ConsoleState) Setup(man *AppStateManager) {
+	self.man = man
+	self.textInputWas = man.TextInputEnabled
+	man.SetTextInput(true)
+}
+
+func (self *ConsoleState) Destroy() {
+	self.man.SetTextInput(self.textInputWas)
+}
+
+func (self *ConsoleState) Pause() {
+}
+
+func (self *ConsoleState) Resume() {
+}
+
+func (self *ConsoleState) Process(time_step float32) {
+}
+
+func (self *ConsoleState) OnKeyDown(key *sdl.Keysym) {
+	if key.Sym == sdl.K_ESCAPE {
+		self.man.Pop()
+	}
+}
+
+func (self *ConsoleState) OnKeyUp(key *sdl.Keysym) {
+}
+
+func (self *ConsoleState) OnMouseMove(x, y, dx, dy float32) {
+}
+
+func (self *ConsoleState) OnMouseClick(x, y float32, button int, down bool) {
+}
+
+func (self *ConsoleState) OnSdlEvent(event *sdl.Event) {
+}
+
+func (self *ConsoleState) OnViewportResize(x, y float32) {
+}
+
+func (self *ConsoleState) RenderBelow() bool {
+	return true
+}
+
+func (self *ConsoleState) UpdateBelow() bool {
+	return true
+}
+
+func (self *ConsoleState) OnMouseWheel(x, y float32, delta int) {
+	self.scroll += delta * 3
+	if self.scroll > len(self.Lines)-1 {
+		self.scroll = len(self.Lines) - 1
+	}
+	if self.scroll < 0 {
+		self.scroll = 0
+	}
+}
+
+func (self *ConsoleState) OnTextInput(text []rune) {
+	tail := append(append([]rune{}, text...), self.input[self.cursor:]...)
+	self.input = append(self.input[:self.cursor], tail...)
+	self.cursor += len(text)
+}
+
+func (self *ConsoleState) OnTextEdit(edit TextEdit) {
+	switch edit.Op {
+	case TEXT_EDIT_BACKSPACE:
+		if self.cursor > 0 {
+			self.input = append(self.input[:self.cursor-1], self.input[self.cursor:]...)
+			self.cursor--
+		}
+	case TEXT_EDIT_DELETE:
+		if self.cursor < len(self.input) {
+			self.input = append(self.input[:self.cursor], self.input[self.cursor+1:]...)
+		}
+	case TEXT_EDIT_LEFT:
+		if self.cursor > 0 {
+			self.cursor--
+		}
+	case TEXT_EDIT_RIGHT:
+		if self.cursor < len(self.input) {
+			self.cursor++
+		}
+	case TEXT_EDIT_WORD_LEFT:
+		for self.cursor > 0 && self.input[self.cursor-1] == ' ' {
+			self.cursor--
+		}
+		for self.cursor > 0 && self.input[self.cursor-1] != ' ' {
+			self.cursor--
+		}
+	case TEXT_EDIT_WORD_RIGHT:
+		for self.cursor < len(self.input) && self.input[self.cursor] != ' ' {
+			self.cursor++
+		}
+		for self.cursor < len(self.input) && self.input[self.cursor] == ' ' {
+			self.cursor++
+		}
+	case TEXT_EDIT_HOME:
+		self.cursor = 0
+	case TEXT_EDIT_END:
+		self.cursor = len(self.input)
+	case TEXT_EDIT_HISTORY_UP:
+		if self.histPos > 0 {
+			self.histPos--
+			self.setInput(self.History[self.histPos])
+		}
+	case TEXT_EDIT_HISTORY_DOWN:
+		if self.histPos < len(self.History)-1 {
+			self.histPos++
+			self.setInput(self.History[self.histPos])
+		} else {
+			self.histPos = len(self.History)
+			self.setInput("")
+		}
+	case TEXT_EDIT_COMPLETE:
+		self.complete()
+	case TEXT_EDIT_PASTE:
+		self.OnTextInput(edit.Text)
+	case TEXT_EDIT_SUBMIT:
+		self.submit()
+	}
+}
+
+func (self *ConsoleState) setInput(text string) {
+	self.input = []rune(text)
+	self.cursor = len(self.input)
+}
+
+func (self *ConsoleState) submit() {
+	line := strings.TrimSpace(string(self.input))
+	self.setInput("")
+	if line == "" {
+		return
+	}
+
+	if len(self.History) == 0 || self.History[len(self.History)-1] != line {
+		self.History = append(self.History, line)
+		if len(self.History) > CONSOLE_MAX_HISTORY {
+			self.History = self.History[1:]
+		}
+	}
+	self.histPos = len(self.History)
+
+	self.Execute(line)
+}
+
+// Complete command name, listing candidates when there are several
+func (self *ConsoleState) complete() {
+	text := string(self.input)
+	if strings.Contains(text, " ") {
+		return
+	}
+
+	matches := self.Registry.Complete(text)
+	if len(matches) == 0 {
+		return
+	}
+	if len(matches) == 1 {
+		self.setInput(matches[0] + " ")
+		return
+	}
+
+	self.Print(strings.Join(matches, "  "))
+	prefix := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	self.setInput(prefix)
+}
+
+// Console covers upper half of the screen
+func (self *ConsoleState) Render(alpha float32) {
+	vp := GetViewport()
+	cam := NewCamera(vp)
+	cam.SetOrthoProjection(-1, 1)
+	cam.SetModelviewOne()
+
+	RenderUIStart()
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	RenderRect(cam, v.MatrixTranslate(vp.Width/2, vp.Height*3/4, 0),
+		vp.Width/2, vp.Height/4, self.Background)
+	gl.Disable(gl.BLEND)
+
+	if self.Font != nil {
+		one := v.MatrixOne()
+		margin := self.Font.CharWidth / 2
+		y := vp.Height/2 + margin
+
+		prompt := "> " + string(self.input[:self.cursor]) + "_" + string(self.input[self.cursor:])
+		self.Font.RenderText(cam, one, margin, y, prompt, self.TextColour)
+
+		for i := len(self.Lines) - 1 - self.scroll; i >= 0; i-- {
+			y += self.Font.CharHeight
+			if y+self.Font.CharHeight > vp.Height {
+				break
+			}
+			self.Font.RenderText(cam, one, margin, y, self.Lines[i], self.TextColour)
+		}
+	}
+
+	RenderUIEnd()
+	gl.Color4ub(255, 255, 255, 255)
+}
+
+func (self *ConsoleState) registerBuiltins() {
+	reg := self.Registry
+
+	reg.RegisterCommand("clear", "clear console", func(args []string) (string, error) {
+		self.Lines = nil
+		return "", nil
+	})
+
+	reg.RegisterCommand("echo", "print arguments", func(args []string) (string, error) {
+		return strings.Join(args, " "), nil
+	})
+
+	reg.RegisterCommand("frame_stats", "show frame times", func(args []string) (string, error) {
+		return self.man.Stats.String(), nil
+	})
+
+	reg.RegisterCommand("textures", "list loaded textures", func(args []string) (string, error) {
+		names := []string{}
+		for name := range GetTextureManager().Textures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		lines := []string{}
+		for _, name := range names {
+			t := GetTextureManager().Textures[name]
+			lines = append(lines, fmt.Sprintf("%s %dx%d", name, t.Width, t.Height))
+		}
+		return strings.Join(lines, "\n"), nil
+	})
+
+	reg.RegisterCommand("gl_resources", "gl_resources [leaks] - GPU memory by kind, or live objects", func(args []string) (string, error) {
+		if len(args) > 0 && args[0] == "leaks" {
+			return GetResourceRegistry().LeakReport(), nil
+		}
+		return GetResourceRegistry().MemoryReport(), nil
+	})
+
+	reg.RegisterCommand("reload_shaders", "recompile all shader programs", func(args []string) (string, error) {
+		if err := GetShaderManager().ReloadAll(); err != nil {
+			return "", err
+		}
+		return "Shaders reloaded", nil
+	})
+
+	reg.RegisterCommand("video_mode", "video_mode <width> <height> [fullscreen|windowed]", func(args []string) (string, error) {
+		if len(args) < 2 {
+			w := self.man.Window
+			return fmt.Sprintf("%dx%d fullscreen=%v", w.Width, w.Height, w.Fullscreen), nil
+		}
+
+		config := self.man.Window
+		width, err := strconv.Atoi(args[0])
+		if err != nil {
+			return "", err
+		}
+		height, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "", err
+		}
+		config.Width, config.Height = width, height
+		if len(args) > 2 {
+			config.Fullscreen = args[2] == "fullscreen"
+		}
+		return "", self.man.SetVideoMode(config)
+	})
+}
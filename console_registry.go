@@ -0,0 +1,178 @@
+package glutils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Console command, gets arguments without the command name and
+// returns text to print
+type ConsoleCommand struct {
+	Name string
+	Help string
+	Run  func(args []string) (string, error)
+}
+
+// Variable tweakable from console
+type ConsoleVar struct {
+	Name string
+	Help string
+	Get  func() string
+	Set  func(value string) error
+}
+
+// Commands and variables available in console. Any state or
+// subsystem may register its own.
+type ConsoleRegistry struct {
+	Commands map[string]*ConsoleCommand
+	Vars     map[string]*ConsoleVar
+}
+
+var consoleRegistry *ConsoleRegistry = NewConsoleRegistry()
+
+func NewConsoleRegistry() *ConsoleRegistry {
+	ret := &ConsoleRegistry{
+		Commands: map[string]*ConsoleCommand{},
+		Vars:     map[string]*ConsoleVar{}}
+	ret.registerVarCommands()
+	return ret
+}
+
+func GetConsoleRegistry() *ConsoleRegistry {
+	return consoleRegistry
+}
+
+func (self *ConsoleRegistry) RegisterCommand(name, help string, run func(args []string) (string, error)) {
+	self.Commands[name] = &ConsoleCommand{name, help, run}
+}
+
+func (self *ConsoleRegistry) RegisterVar(variable *ConsoleVar) {
+	self.Vars[variable.Name] = variable
+}
+
+func (self *ConsoleRegistry) Unregister(name string) {
+	delete(self.Commands, name)
+	delete(self.Vars, name)
+}
+
+func (self *ConsoleRegistry) IntVar(name, help string, value *int) {
+	self.RegisterVar(&ConsoleVar{name, help,
+		func() string { return strconv.Itoa(*value) },
+		func(text string) error {
+			parsed, err := strconv.Atoi(text)
+			if err == nil {
+				*value = parsed
+			}
+			return err
+		}})
+}
+
+func (self *ConsoleRegistry) FloatVar(name, help string, value *float32) {
+	self.RegisterVar(&ConsoleVar{name, help,
+		func() string { return strconv.FormatFloat(float64(*value), 'g', -1, 32) },
+		func(text string) error {
+			parsed, err := strconv.ParseFloat(text, 32)
+			if err == nil {
+				*value = float32(parsed)
+			}
+			return err
+		}})
+}
+
+func (self *ConsoleRegistry) BoolVar(name, help string, value *bool) {
+	self.RegisterVar(&ConsoleVar{name, help,
+		func() string { return strconv.FormatBool(*value) },
+		func(text string) error {
+			parsed, err := strconv.ParseBool(text)
+			if err == nil {
+				*value = parsed
+			}
+			return err
+		}})
+}
+
+func (self *ConsoleRegistry) StringVar(name, help string, value *string) {
+	self.RegisterVar(&ConsoleVar{name, help,
+		func() string { return *value },
+		func(text string) error {
+			*value = text
+			return nil
+		}})
+}
+
+// Run command line. Variable name alone prints its value, followed
+// by a value sets it.
+func (self *ConsoleRegistry) Execute(line string) (string, error) {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	if command := self.Commands[args[0]]; command != nil {
+		return command.Run(args[1:])
+	}
+
+	if variable := self.Vars[args[0]]; variable != nil {
+		if len(args) == 1 {
+			return variable.Name + " = " + variable.Get(), nil
+		}
+		if err := variable.Set(strings.Join(args[1:], " ")); err != nil {
+			return "", err
+		}
+		return variable.Name + " = " + variable.Get(), nil
+	}
+
+	return "", errors.New("Unknown command " + args[0])
+}
+
+// Sorted names of commands and variables starting with prefix
+func (self *ConsoleRegistry) Complete(prefix string) []string {
+	ret := []string{}
+	for name := range self.Commands {
+		if strings.HasPrefix(name, prefix) {
+			ret = append(ret, name)
+		}
+	}
+	for name := range self.Vars {
+		if strings.HasPrefix(name, prefix) {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (self *ConsoleRegistry) registerVarCommands() {
+	self.RegisterCommand("help", "list commands, or show help of one", func(args []string) (string, error) {
+		if len(args) > 0 {
+			if command := self.Commands[args[0]]; command != nil {
+				return command.Name + " - " + command.Help, nil
+			}
+			if variable := self.Vars[args[0]]; variable != nil {
+				return variable.Name + " - " + variable.Help, nil
+			}
+			return "", errors.New("Unknown command " + args[0])
+		}
+
+		lines := []string{}
+		for _, name := range self.Complete("") {
+			if command := self.Commands[name]; command != nil {
+				lines = append(lines, command.Name+" - "+command.Help)
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	})
+
+	self.RegisterCommand("vars", "list variables with values", func(args []string) (string, error) {
+		lines := []string{}
+		for _, name := range self.Complete("") {
+			if variable := self.Vars[name]; variable != nil {
+				lines = append(lines, fmt.Sprintf("%s = %s", name, variable.Get()))
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	})
+}
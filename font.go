@@ -0,0 +1,78 @@
+package glutils
+
+import (
+	"github.com/pzsz/gl"
+	v "github.com/pzsz/lin3dmath"
+)
+
+// Fixed width font drawn from texture holding grid of glyphs, in
+// rows from the top of image
+type BitmapFont struct {
+	Texture       *Texture
+	Columns, Rows int
+	// Character in the top left cell
+	FirstChar rune
+
+	// Size of character on screen
+	CharWidth, CharHeight float32
+}
+
+// Font with characters drawn at size of texture cell
+func NewBitmapFont(texture *Texture, columns, rows int, firstChar rune) *BitmapFont {
+	return &BitmapFont{
+		Texture:    texture,
+		Columns:    columns,
+		Rows:       rows,
+		FirstChar:  firstChar,
+		CharWidth:  float32(texture.Width) / float32(columns),
+		CharHeight: float32(texture.Height) / float32(rows)}
+}
+
+func (self *BitmapFont) TextWidth(text string) float32 {
+	return float32(len([]rune(text))) * self.CharWidth
+}
+
+// Draw single line with bottom left corner at x,y. Characters missing
+// from the font are skipped.
+func (self *BitmapFont) RenderText(cam *Camera, m *v.Matrix4, x, y float32, text string, colour Colour) {
+	defer checkGL("BitmapFont.RenderText")
+	cam.LoadProjection()
+	cam.LoadModelview(m)
+
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.Enable(gl.TEXTURE_2D)
+	self.Texture.tex.Bind(gl.TEXTURE_2D)
+
+	cellU := 1 / float32(self.Columns)
+	cellV := 1 / float32(self.Rows)
+
+	gl.Color4ub(colour.R, colour.G, colour.B, colour.A)
+	gl.Begin(gl.QUADS)
+	for _, c := range text {
+		cell := int(c - self.FirstChar)
+		if cell >= 0 && cell < self.Columns*self.Rows {
+			u0 := float32(cell%self.Columns) * cellU
+			v0 := float32(cell/self.Columns) * cellV
+
+			gl.TexCoord2f(u0, v0+cellV)
+			gl.Vertex3f(x, y, 0)
+
+			gl.TexCoord2f(u0+cellU, v0+cellV)
+			gl.Vertex3f(x+self.CharWidth, y, 0)
+
+			gl.TexCoord2f(u0+cellU, v0)
+			gl.Vertex3f(x+self.CharWidth, y+self.CharHeight, 0)
+
+			gl.TexCoord2f(u0, v0)
+			gl.Vertex3f(x, y+self.CharHeight, 0)
+		}
+		x += self.CharWidth
+	}
+	gl.End()
+
+	gl.Disable(gl.TEXTURE_2D)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.Disable(gl.BLEND)
+	gl.Color4ub(255, 255, 255, 255)
+}
@@ -73,6 +73,11 @@ func (s *FpsController) RotateBy(deltaHor, deltaVer float32) {
 	}
 }
 
+// Rotate by filtered movement of last frame
+func (s *FpsController) RotateByMouse(mouse *RelativeMouse) {
+	s.RotateBy(mouse.DeltaX, mouse.DeltaY)
+}
+
 func (s *FpsController) SetupCamera() {
 	hor := v.MatrixRotate(v.Angle(-s.HorAxis), 0, 1, 0)
 	ver := v.MatrixRotate(v.Angle(-s.VerAxis), 1, 0, 0)
@@ -0,0 +1,132 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+)
+
+// Results of frustum classification
+const (
+	FRUSTUM_OUTSIDE = iota
+	FRUSTUM_INSIDE
+	FRUSTUM_INTERSECT
+)
+
+// Bounding sphere
+type Sphere struct {
+	Centre v.Vector3f
+	Radius float32
+}
+
+// Six planes with normals pointing inside: left, right, bottom, top,
+// near and far
+type Frustum struct {
+	Planes [6]Plane
+}
+
+// Extract planes from projection*modelview matrix. Planes are in
+// world space when modelview holds camera transform only.
+func NewFrustum(m *v.Matrix4) Frustum {
+	var rows [4][4]float32
+	for i := range rows {
+		rows[i] = [4]float32{m[i], m[4+i], m[8+i], m[12+i]}
+	}
+	r3 := rows[3]
+
+	var ret Frustum
+	for i := range ret.Planes {
+		// Fourth row plus or minus first, second and third
+		r := rows[i/2]
+		s := float32(1)
+		if i%2 == 1 {
+			s = -1
+		}
+
+		normal := v.Vector3f{r3[0] + s*r[0], r3[1] + s*r[1], r3[2] + s*r[2]}
+		d := r3[3] + s*r[3]
+		length := vecLength(normal)
+		if length != 0 {
+			normal = vecScale(normal, 1/length)
+			d /= length
+		}
+		ret.Planes[i] = Plane{normal, d}
+	}
+	return ret
+}
+
+// Frustum of current matrices
+func (self *Camera) Frustum() Frustum {
+	combined := matrixMul(&self.ProjectionMatrix, &self.ModelviewMatrix)
+	return NewFrustum(&combined)
+}
+
+// Point on a plane counts as inside
+func (self *Frustum) ClassifyPoint(point v.Vector3f) int {
+	for i := range self.Planes {
+		if self.Planes[i].Distance(point) < 0 {
+			return FRUSTUM_OUTSIDE
+		}
+	}
+	return FRUSTUM_INSIDE
+}
+
+func (self *Frustum) ClassifySphere(sphere Sphere) int {
+	ret := FRUSTUM_INSIDE
+	for i := range self.Planes {
+		d := self.Planes[i].Distance(sphere.Centre)
+		if d < -sphere.Radius {
+			return FRUSTUM_OUTSIDE
+		}
+		if d < sphere.Radius {
+			ret = FRUSTUM_INTERSECT
+		}
+	}
+	return ret
+}
+
+// Tests corners furthest along and against each plane normal
+func (self *Frustum) ClassifyAABB(box AABB) int {
+	ret := FRUSTUM_INSIDE
+	for i := range self.Planes {
+		plane := &self.Planes[i]
+		far, near := box.Max, box.Min
+		if plane.Normal.X < 0 {
+			far.X, near.X = box.Min.X, box.Max.X
+		}
+		if plane.Normal.Y < 0 {
+			far.Y, near.Y = box.Min.Y, box.Max.Y
+		}
+		if plane.Normal.Z < 0 {
+			far.Z, near.Z = box.Min.Z, box.Max.Z
+		}
+
+		if plane.Distance(far) < 0 {
+			return FRUSTUM_OUTSIDE
+		}
+		if plane.Distance(near) < 0 {
+			ret = FRUSTUM_INTERSECT
+		}
+	}
+	return ret
+}
+
+// Append indices of spheres that aren't outside to visible, which may
+// be reused between frames
+func (self *Frustum) CullSpheres(spheres []Sphere, visible []int) []int {
+	for i := range spheres {
+		if self.ClassifySphere(spheres[i]) != FRUSTUM_OUTSIDE {
+			visible = append(visible, i)
+		}
+	}
+	return visible
+}
+
+// Append indices of boxes that aren't outside to visible, which may be
+// reused between frames
+func (self *Frustum) CullAABBs(boxes []AABB, visible []int) []int {
+	for i := range boxes {
+		if self.ClassifyAABB(boxes[i]) != FRUSTUM_OUTSIDE {
+			visible = append(visible, i)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,142 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func planeNearlyEqual(a, b Plane, epsilon float32) bool {
+	return vecNearlyEqual(a.Normal, b.Normal, epsilon) && nearlyEqual(a.D, b.D, epsilon)
+}
+
+func TestNewFrustum(t *testing.T) {
+	// 60 degree vertical fov, 4:3, near 1 and far 100, looking down -Z
+	frustum := testCameras()[0].cam.Frustum()
+	ys := float32(math.Tan(math.Pi / 6))
+	xs := ys * 800 / 600
+
+	want := [6]Plane{
+		{vecNormalize(v.Vector3f{1, 0, -xs}), 0},
+		{vecNormalize(v.Vector3f{-1, 0, -xs}), 0},
+		{vecNormalize(v.Vector3f{0, 1, -ys}), 0},
+		{vecNormalize(v.Vector3f{0, -1, -ys}), 0},
+		{v.Vector3f{0, 0, -1}, -1},
+		{v.Vector3f{0, 0, 1}, 100},
+	}
+	names := []string{"left", "right", "bottom", "top", "near", "far"}
+
+	for i := range want {
+		if !planeNearlyEqual(frustum.Planes[i], want[i], testEpsilon) {
+			t.Errorf("%s plane %+v, want %+v", names[i], frustum.Planes[i], want[i])
+		}
+	}
+}
+
+func TestFrustumMovedCamera(t *testing.T) {
+	// Eye at 5,3,8 looking at origin, near 0.5 and far 50
+	cam := testCameras()[1].cam
+	frustum := cam.Frustum()
+	forward := vecNormalize(vecScale(cam.EyePos, -1))
+
+	cases := []struct {
+		name  string
+		point v.Vector3f
+		want  int
+	}{
+		{"look at point", v.Vector3f{0, 0, 0}, FRUSTUM_INSIDE},
+		{"far along view", vecAdd(cam.EyePos, vecScale(forward, 49)), FRUSTUM_INSIDE},
+		{"past far plane", vecAdd(cam.EyePos, vecScale(forward, 51)), FRUSTUM_OUTSIDE},
+		{"before near plane", vecAdd(cam.EyePos, vecScale(forward, 0.4)), FRUSTUM_OUTSIDE},
+		{"just past near plane", vecAdd(cam.EyePos, forward), FRUSTUM_INSIDE},
+		{"behind eye", vecSub(cam.EyePos, forward), FRUSTUM_OUTSIDE},
+	}
+
+	for _, c := range cases {
+		if got := frustum.ClassifyPoint(c.point); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFrustumClassify(t *testing.T) {
+	frustum := testCameras()[0].cam.Frustum()
+
+	points := []struct {
+		name  string
+		point v.Vector3f
+		want  int
+	}{
+		{"centre", v.Vector3f{0, 0, -10}, FRUSTUM_INSIDE},
+		{"before near", v.Vector3f{0, 0, -0.5}, FRUSTUM_OUTSIDE},
+		{"past far", v.Vector3f{0, 0, -101}, FRUSTUM_OUTSIDE},
+		{"behind", v.Vector3f{0, 0, 5}, FRUSTUM_OUTSIDE},
+		{"right of view", v.Vector3f{20, 0, -10}, FRUSTUM_OUTSIDE},
+		{"above view", v.Vector3f{0, 6, -10}, FRUSTUM_OUTSIDE},
+	}
+	for _, c := range points {
+		if got := frustum.ClassifyPoint(c.point); got != c.want {
+			t.Errorf("point %s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+
+	spheres := []struct {
+		name   string
+		sphere Sphere
+		want   int
+	}{
+		{"inside", Sphere{v.Vector3f{0, 0, -10}, 1}, FRUSTUM_INSIDE},
+		{"around frustum", Sphere{v.Vector3f{0, 0, -10}, 500}, FRUSTUM_INTERSECT},
+		{"across near", Sphere{v.Vector3f{0, 0, -0.5}, 1}, FRUSTUM_INTERSECT},
+		{"centre outside right", Sphere{v.Vector3f{8.2, 0, -10}, 1}, FRUSTUM_INTERSECT},
+		{"right of view", Sphere{v.Vector3f{20, 0, -10}, 1}, FRUSTUM_OUTSIDE},
+		{"behind", Sphere{v.Vector3f{0, 0, 5}, 1}, FRUSTUM_OUTSIDE},
+	}
+	for _, c := range spheres {
+		if got := frustum.ClassifySphere(c.sphere); got != c.want {
+			t.Errorf("sphere %s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+
+	boxes := []struct {
+		name string
+		box  AABB
+		want int
+	}{
+		{"inside", AABB{v.Vector3f{-1, -1, -11}, v.Vector3f{1, 1, -9}}, FRUSTUM_INSIDE},
+		{"around frustum", AABB{v.Vector3f{-1000, -1000, -1000}, v.Vector3f{1000, 1000, 1000}}, FRUSTUM_INTERSECT},
+		{"across near", AABB{v.Vector3f{-1, -1, -1.5}, v.Vector3f{1, 1, -0.5}}, FRUSTUM_INTERSECT},
+		{"across far", AABB{v.Vector3f{-1, -1, -110}, v.Vector3f{1, 1, -90}}, FRUSTUM_INTERSECT},
+		{"off to the side", AABB{v.Vector3f{10, 10, -3}, v.Vector3f{11, 11, -2}}, FRUSTUM_OUTSIDE},
+		{"behind", AABB{v.Vector3f{-1, -1, 1}, v.Vector3f{1, 1, 2}}, FRUSTUM_OUTSIDE},
+	}
+	for _, c := range boxes {
+		if got := frustum.ClassifyAABB(c.box); got != c.want {
+			t.Errorf("box %s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFrustumCull(t *testing.T) {
+	frustum := testCameras()[0].cam.Frustum()
+
+	spheres := []Sphere{
+		{v.Vector3f{0, 0, -10}, 1},
+		{v.Vector3f{0, 0, 5}, 1},
+		{v.Vector3f{0, 0, -0.5}, 1},
+	}
+	visible := frustum.CullSpheres(spheres, []int{7})
+	if !reflect.DeepEqual(visible, []int{7, 0, 2}) {
+		t.Errorf("spheres: %v", visible)
+	}
+
+	boxes := []AABB{
+		{v.Vector3f{-1, -1, 1}, v.Vector3f{1, 1, 2}},
+		{v.Vector3f{-1, -1, -11}, v.Vector3f{1, 1, -9}},
+	}
+	visible = frustum.CullAABBs(boxes, visible[:0])
+	if !reflect.DeepEqual(visible, []int{1}) {
+		t.Errorf("boxes: %v", visible)
+	}
+}
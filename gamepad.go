@@ -0,0 +1,243 @@
+package glutils
+
+import (
+	"github.com/banthar/Go-SDL/sdl"
+)
+
+// Gamepad device as reported by Platform
+type GamepadInfo struct {
+	Name    string
+	Axes    int
+	Buttons int
+	Hats    int
+}
+
+// Optional interface for states receiving gamepad input. Hats are
+// reported as four extra buttons each (up, right, down, left),
+// numbered after the real ones. Axis values are in -1..1 range with
+// dead zone already applied.
+type GamepadHandler interface {
+	OnGamepadButton(pad, button int, down bool)
+	OnGamepadAxis(pad, axis int, value float32)
+}
+
+var hatDirections = []uint8{sdl.HAT_UP, sdl.HAT_RIGHT, sdl.HAT_DOWN, sdl.HAT_LEFT}
+
+type gamepadState struct {
+	info    GamepadInfo
+	axes    []float32
+	buttons []bool
+}
+
+// Keeps state of opened gamepads and turns SDL joystick events into
+// GamepadHandler calls
+type GamepadManager struct {
+	// Part of axis range, from centre, that reads as 0
+	DeadZone float32
+	// Seconds between device rescans, 0 turns them off. SDL 1.2 doesn't
+	// report plugged devices, so rescanning is the only way to find them.
+	// Reopened devices lose their state, so rescan waits until no button
+	// is held and all axes are centred.
+	RescanInterval float32
+
+	pads        []*gamepadState
+	sinceRescan float32
+}
+
+func NewGamepadManager() *GamepadManager {
+	return &GamepadManager{DeadZone: 0.2}
+}
+
+func (self *GamepadManager) Count() int {
+	return len(self.pads)
+}
+
+// Description of pad, zero for unknown pads
+func (self *GamepadManager) Info(pad int) GamepadInfo {
+	if !self.known(pad) {
+		return GamepadInfo{}
+	}
+	return self.pads[pad].info
+}
+
+func (self *GamepadManager) known(pad int) bool {
+	return pad >= 0 && pad < len(self.pads)
+}
+
+func (self *GamepadManager) devices() []GamepadInfo {
+	ret := make([]GamepadInfo, len(self.pads))
+	for i, pad := range self.pads {
+		ret[i] = pad.info
+	}
+	return ret
+}
+
+// Current axis value, 0 for unknown pads and axes
+func (self *GamepadManager) Axis(pad, axis int) float32 {
+	if !self.known(pad) || axis < 0 || axis >= len(self.pads[pad].axes) {
+		return 0
+	}
+	return self.pads[pad].axes[axis]
+}
+
+func (self *GamepadManager) Button(pad, button int) bool {
+	if !self.known(pad) || button < 0 || button >= len(self.pads[pad].buttons) {
+		return false
+	}
+	return self.pads[pad].buttons[button]
+}
+
+// True when some button is held or axis isn't centred
+func (self *GamepadManager) active() bool {
+	for _, pad := range self.pads {
+		for _, down := range pad.buttons {
+			if down {
+				return true
+			}
+		}
+		for _, value := range pad.axes {
+			if value != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Report held buttons as released and centre axes, as if devices sent
+// such events
+func (self *GamepadManager) releaseAll(man *AppStateManager) {
+	for i, pad := range self.pads {
+		which := uint8(i)
+		for button, down := range pad.buttons {
+			if !down {
+				continue
+			}
+			if button < pad.info.Buttons {
+				self.handleEvent(man, &sdl.JoyButtonEvent{Type: sdl.JOYBUTTONUP,
+					Which: which, Button: uint8(button), State: 0})
+			} else {
+				hat := uint8((button - pad.info.Buttons) / 4)
+				self.handleEvent(man, &sdl.JoyHatEvent{Type: sdl.JOYHATMOTION,
+					Which: which, Hat: hat, Value: sdl.HAT_CENTERED})
+			}
+		}
+		for axis, value := range pad.axes {
+			if value != 0 {
+				self.handleEvent(man, &sdl.JoyAxisEvent{Type: sdl.JOYAXISMOTION,
+					Which: which, Axis: uint8(axis), Value: 0})
+			}
+		}
+	}
+}
+
+// Replace known devices, state of all of them is reset
+func (self *GamepadManager) setDevices(infos []GamepadInfo) {
+	self.pads = nil
+	for _, info := range infos {
+		self.pads = append(self.pads, &gamepadState{
+			info:    info,
+			axes:    make([]float32, info.Axes),
+			buttons: make([]bool, info.Buttons+4*info.Hats)})
+	}
+	self.sinceRescan = 0
+}
+
+// Map raw axis value to -1..1, with dead zone around centre
+func (self *GamepadManager) normaliseAxis(value int16) float32 {
+	v := float32(value) / 32767
+	if v < -1 {
+		v = -1
+	}
+
+	if v > -self.DeadZone && v < self.DeadZone {
+		return 0
+	}
+
+	// Rescale so output starts from 0 at dead zone edge
+	if v > 0 {
+		return (v - self.DeadZone) / (1 - self.DeadZone)
+	}
+	return (v + self.DeadZone) / (1 - self.DeadZone)
+}
+
+func (self *GamepadManager) handleEvent(man *AppStateManager, event sdl.Event) {
+	switch event.(type) {
+	case *sdl.JoyAxisEvent:
+		jevent := event.(*sdl.JoyAxisEvent)
+		pad, axis := int(jevent.Which), int(jevent.Axis)
+		if !self.known(pad) || axis < 0 || axis >= len(self.pads[pad].axes) {
+			return
+		}
+
+		value := self.normaliseAxis(jevent.Value)
+		if self.pads[pad].axes[axis] == value {
+			return
+		}
+		self.pads[pad].axes[axis] = value
+
+		man.dispatchInput(event, func(state AppState) {
+			if handler, ok := state.(GamepadHandler); ok {
+				handler.OnGamepadAxis(pad, axis, value)
+			}
+		})
+		break
+	case *sdl.JoyButtonEvent:
+		jevent := event.(*sdl.JoyButtonEvent)
+		self.setButton(man, event, int(jevent.Which), int(jevent.Button), jevent.State == 1)
+		break
+	case *sdl.JoyHatEvent:
+		jevent := event.(*sdl.JoyHatEvent)
+		pad := int(jevent.Which)
+		if !self.known(pad) {
+			return
+		}
+
+		first := self.pads[pad].info.Buttons + 4*int(jevent.Hat)
+		for i, dir := range hatDirections {
+			self.setButton(man, event, pad, first+i, jevent.Value&dir != 0)
+		}
+		break
+	}
+}
+
+func (self *GamepadManager) setButton(man *AppStateManager, event sdl.Event, pad, button int, down bool) {
+	if !self.known(pad) || button < 0 || button >= len(self.pads[pad].buttons) {
+		return
+	}
+	if self.pads[pad].buttons[button] == down {
+		return
+	}
+	self.pads[pad].buttons[button] = down
+
+	man.dispatchInput(event, func(state AppState) {
+		if handler, ok := state.(GamepadHandler); ok {
+			handler.OnGamepadButton(pad, button, down)
+		}
+	})
+}
+
+// Reopen gamepads found by platform. Held buttons and off centre axes
+// are reported released first.
+func (self *AppStateManager) RescanGamepads() {
+	if self.Gamepads != nil {
+		self.Gamepads.releaseAll(self)
+		self.Gamepads.setDevices(self.Platform.OpenGamepads())
+		if self.Recorder != nil {
+			self.Recorder.RecordGamepads(self.Gamepads.devices())
+		}
+	}
+}
+
+// Devices don't change during replay, recording says which were used
+func (self *AppStateManager) updateGamepads(time_step float32) {
+	pads := self.Gamepads
+	if pads == nil || pads.RescanInterval <= 0 || self.Replayer != nil {
+		return
+	}
+
+	pads.sinceRescan += time_step
+	if pads.sinceRescan >= pads.RescanInterval && !pads.active() {
+		self.RescanGamepads()
+	}
+}
@@ -0,0 +1,40 @@
+package glutils
+
+import (
+	"testing"
+)
+
+func TestGamepadBounds(t *testing.T) {
+	pads := NewGamepadManager()
+	info := GamepadInfo{"pad", 2, 3, 0}
+	pads.setDevices([]GamepadInfo{info})
+	pads.pads[0].axes[1] = 0.5
+	pads.pads[0].buttons[2] = true
+
+	cases := []struct {
+		name       string
+		pad, index int
+		info       GamepadInfo
+		axis       float32
+		button     bool
+	}{
+		{"last axis and button", 0, 1, info, 0.5, false},
+		{"last button", 0, 2, info, 0, true},
+		{"negative index", 0, -1, info, 0, false},
+		{"index past end", 0, 3, info, 0, false},
+		{"negative pad", -1, 0, GamepadInfo{}, 0, false},
+		{"pad past end", 1, 0, GamepadInfo{}, 0, false},
+	}
+
+	for _, c := range cases {
+		if got := pads.Info(c.pad); got != c.info {
+			t.Errorf("%s: Info = %v, want %v", c.name, got, c.info)
+		}
+		if got := pads.Axis(c.pad, c.index); got != c.axis {
+			t.Errorf("%s: Axis = %v, want %v", c.name, got, c.axis)
+		}
+		if got := pads.Button(c.pad, c.index); got != c.button {
+			t.Errorf("%s: Button = %v, want %v", c.name, got, c.button)
+		}
+	}
+}
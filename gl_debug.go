@@ -0,0 +1,92 @@
+package glutils
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pzsz/gl"
+	"log"
+	"strings"
+)
+
+var FLAG_glcheck *bool = flag.Bool("glcheck", false, "check GL errors after every glutils call")
+
+// Receives GL errors and driver debug messages, they go to log package
+// when nil
+var GLLogger func(message string)
+
+var glErrorNames = map[gl.GLenum]string{
+	gl.INVALID_ENUM:                  "INVALID_ENUM",
+	gl.INVALID_VALUE:                 "INVALID_VALUE",
+	gl.INVALID_OPERATION:             "INVALID_OPERATION",
+	gl.STACK_OVERFLOW:                "STACK_OVERFLOW",
+	gl.STACK_UNDERFLOW:               "STACK_UNDERFLOW",
+	gl.OUT_OF_MEMORY:                 "OUT_OF_MEMORY",
+	gl.INVALID_FRAMEBUFFER_OPERATION: "INVALID_FRAMEBUFFER_OPERATION",
+}
+
+// Error reported by gl.GetError after glutils call
+type GLError struct {
+	Code gl.GLenum
+	// Function of glutils that made the call
+	Function string
+	// Caller of glutils, as file:line
+	Site string
+}
+
+func (self *GLError) Error() string {
+	name := glErrorNames[self.Code]
+	if name == "" {
+		name = fmt.Sprintf("0x%x", uint32(self.Code))
+	}
+	return fmt.Sprintf("GL error %s in %s called at %s", name, self.Function, self.Site)
+}
+
+func logGL(message string) {
+	if GLLogger != nil {
+		GLLogger(message)
+	} else {
+		log.Println(message)
+	}
+}
+
+// Report all pending GL errors, checked with -glcheck only
+func checkGL(function string) {
+	if !*FLAG_glcheck {
+		return
+	}
+
+	for code := gl.GetError(); code != gl.NO_ERROR; code = gl.GetError() {
+		logGL((&GLError{code, function, callSite()}).Error())
+	}
+}
+
+// Space separated extension string of current context
+func HasGLExtension(name string) bool {
+	for _, ext := range strings.Fields(gl.GetString(gl.EXTENSIONS)) {
+		if ext == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Called once GL context exists. With KHR_debug or ARB_debug_output
+// driver messages go to the logger too, otherwise only glGetError is
+// checked.
+func setupGLDebug() {
+	if !*FLAG_glcheck {
+		return
+	}
+
+	// Errors left by context creation aren't ours
+	for gl.GetError() != gl.NO_ERROR {
+	}
+
+	khr := HasGLExtension("GL_KHR_debug")
+	if !khr && !HasGLExtension("GL_ARB_debug_output") {
+		return
+	}
+	if !installGLDebugOutput(khr) {
+		logGL("GL debug output advertised, but its entry point is missing")
+	}
+}
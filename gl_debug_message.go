@@ -0,0 +1,34 @@
+package glutils
+
+// Exported functions can't share file with C definitions, trampoline
+// calling this one is in gl_debug_output.go
+
+/*
+#include <SDL_opengl.h>
+*/
+import "C"
+
+import (
+	"fmt"
+)
+
+var glDebugSeverityNames = map[C.GLenum]string{
+	0x9146: "high",
+	0x9147: "medium",
+	0x9148: "low",
+}
+
+//export glutilsDebugMessage
+func glutilsDebugMessage(source, kind C.GLenum, id C.GLuint, severity C.GLenum, length C.GLsizei, message *C.char) {
+	name, ok := glDebugSeverityNames[severity]
+	if !ok {
+		// Notifications, drivers send lots of them
+		return
+	}
+
+	text := C.GoString(message)
+	if length >= 0 {
+		text = C.GoStringN(message, C.int(length))
+	}
+	logGL(fmt.Sprintf("GL debug message 0x%x, %s severity: %s", uint32(id), name, text))
+}
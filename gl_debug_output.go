@@ -0,0 +1,70 @@
+package glutils
+
+/*
+#cgo pkg-config: sdl
+#include <stdlib.h>
+#include <SDL.h>
+#include <SDL_opengl.h>
+
+#ifndef APIENTRY
+#define APIENTRY
+#endif
+
+typedef void (APIENTRY *glutilsDebugProc)(GLenum source, GLenum type,
+	GLuint id, GLenum severity, GLsizei length, const char *message,
+	const void *userParam);
+typedef void (APIENTRY *glutilsDebugMessageCallbackProc)(
+	glutilsDebugProc callback, const void *userParam);
+
+extern void glutilsDebugMessage(GLenum source, GLenum type, GLuint id,
+	GLenum severity, GLsizei length, char *message);
+
+static void APIENTRY glutilsDebugTrampoline(GLenum source, GLenum type,
+	GLuint id, GLenum severity, GLsizei length, const char *message,
+	const void *userParam) {
+	glutilsDebugMessage(source, type, id, severity, length, (char *)message);
+}
+
+// KHR_debug and ARB_debug_output entry points take the same arguments
+static int glutilsInstallDebugCallback(const char *name) {
+	glutilsDebugMessageCallbackProc install =
+		(glutilsDebugMessageCallbackProc)SDL_GL_GetProcAddress(name);
+	if (install == NULL) {
+		return 0;
+	}
+	install(glutilsDebugTrampoline, NULL);
+	return 1;
+}
+*/
+import "C"
+
+import (
+	"github.com/pzsz/gl"
+	"unsafe"
+)
+
+const (
+	glDebugOutput            gl.GLenum = 0x92E0
+	glDebugOutputSynchronous gl.GLenum = 0x8242
+)
+
+// Pass driver debug messages to logGL. Synchronous output makes them
+// arrive inside the GL call that caused them, so callSite is right.
+func installGLDebugOutput(khr bool) bool {
+	name := "glDebugMessageCallbackARB"
+	if khr {
+		name = "glDebugMessageCallback"
+	}
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	if C.glutilsInstallDebugCallback(cname) == 0 {
+		return false
+	}
+	// ARB output is on in debug contexts only and can't be enabled
+	if khr {
+		gl.Enable(glDebugOutput)
+	}
+	gl.Enable(glDebugOutputSynchronous)
+	return true
+}
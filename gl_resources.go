@@ -0,0 +1,166 @@
+package glutils
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pzsz/gl"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+var FLAG_glleaks *bool = flag.Bool("glleaks", false, "report GL objects left alive at exit")
+
+// Kinds of GL resources
+const (
+	RESOURCE_TEXTURE = iota + 1
+	RESOURCE_BUFFER
+	RESOURCE_SHADER
+	RESOURCE_PROGRAM
+)
+
+var resourceKindNames = map[int]string{
+	RESOURCE_TEXTURE: "texture",
+	RESOURCE_BUFFER:  "buffer",
+	RESOURCE_SHADER:  "shader",
+	RESOURCE_PROGRAM: "program",
+}
+
+// GL object created through glutils
+type GLResource struct {
+	Kind int
+	Name string
+	// Estimated GPU memory
+	Bytes int
+	// Caller outside glutils that created it, as file:line
+	Site string
+	// State whose Setup, Process, Render or input handler created it.
+	// Nil for assets shared through managers and objects created
+	// outside of states.
+	Owner AppState
+
+	object interface {
+		Destroy()
+	}
+}
+
+// Keeps track of all live GL objects, keyed by their wrappers
+type ResourceRegistry struct {
+	Resources map[interface{}]*GLResource
+}
+
+var resourceRegistry *ResourceRegistry = NewResourceRegistry()
+
+func NewResourceRegistry() *ResourceRegistry {
+	return &ResourceRegistry{Resources: map[interface{}]*GLResource{}}
+}
+
+func GetResourceRegistry() *ResourceRegistry {
+	return resourceRegistry
+}
+
+func (self *ResourceRegistry) track(object interface{ Destroy() }, kind int, name string, bytes int) *GLResource {
+	res := &GLResource{
+		Kind:   kind,
+		Name:   name,
+		Bytes:  bytes,
+		Site:   callSite(),
+		Owner:  GetManager().current,
+		object: object}
+	self.Resources[object] = res
+	return res
+}
+
+func (self *ResourceRegistry) resize(object interface{}, bytes int) {
+	if res := self.Resources[object]; res != nil {
+		res.Bytes = bytes
+	}
+}
+
+func (self *ResourceRegistry) untrack(object interface{}) {
+	delete(self.Resources, object)
+}
+
+// Change owner of object, nil makes it shared
+func (self *ResourceRegistry) SetOwner(object interface{}, owner AppState) {
+	if res := self.Resources[object]; res != nil {
+		res.Owner = owner
+	}
+}
+
+// Destroy every object owned by state
+func (self *ResourceRegistry) FreeOwner(owner AppState) {
+	for _, res := range self.Resources {
+		if res.Owner == owner {
+			// Destroy removes it from Resources
+			res.object.Destroy()
+		}
+	}
+}
+
+// Sum of Bytes by kind
+func (self *ResourceRegistry) MemoryUsage() map[int]int {
+	ret := map[int]int{}
+	for _, res := range self.Resources {
+		ret[res.Kind] += res.Bytes
+	}
+	return ret
+}
+
+// Memory usage by kind, one line each
+func (self *ResourceRegistry) MemoryReport() string {
+	usage := self.MemoryUsage()
+	lines := []string{}
+	total := 0
+	for kind := RESOURCE_TEXTURE; kind <= RESOURCE_PROGRAM; kind++ {
+		lines = append(lines, fmt.Sprintf("%s: %d KB", resourceKindNames[kind], usage[kind]/1024))
+		total += usage[kind]
+	}
+	lines = append(lines, fmt.Sprintf("total: %d KB", total/1024))
+	return strings.Join(lines, "\n")
+}
+
+// Live objects with place they were created at, empty when nothing
+// is left
+func (self *ResourceRegistry) LeakReport() string {
+	lines := []string{}
+	for _, res := range self.Resources {
+		lines = append(lines, fmt.Sprintf("%s %s (%d bytes) created at %s",
+			resourceKindNames[res.Kind], res.Name, res.Bytes, res.Site))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// First caller outside glutils
+func callSite() string {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(3, pc)
+	frames := runtime.CallersFrames(pc[:n])
+
+	site := "unknown"
+	for {
+		frame, more := frames.Next()
+		site = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		if !strings.Contains(frame.Function, "/glutils.") || !more {
+			return site
+		}
+	}
+}
+
+// Estimated size of texture with its mipmaps
+func textureBytes(t *Texture) int {
+	bpp := 4
+	switch t.Setup.InternalFormat {
+	case gl.ALPHA, gl.LUMINANCE:
+		bpp = 1
+	case gl.RGB:
+		bpp = 3
+	}
+
+	size := t.Width * t.Height * bpp
+	if t.Setup.Mipmaps {
+		size += size / 3
+	}
+	return size
+}
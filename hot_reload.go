@@ -0,0 +1,76 @@
+package glutils
+
+import (
+	"log"
+	"time"
+)
+
+// Polls modification times of textures and shaders loaded through the
+// managers and reloads changed ones in place. Set it as manager Watcher
+// to have it updated between frames.
+type AssetWatcher struct {
+	// Seconds between polls
+	Interval float32
+	// Called when reload fails, error is logged when nil
+	OnError func(name string, err error)
+
+	modTimes map[string]time.Time
+	left     float32
+}
+
+func NewAssetWatcher() *AssetWatcher {
+	return &AssetWatcher{
+		Interval: 1,
+		modTimes: map[string]time.Time{}}
+}
+
+func (self *AssetWatcher) Update(time_step float32) {
+	self.left -= time_step
+	if self.left > 0 {
+		return
+	}
+	self.left = self.Interval
+
+	self.Poll()
+}
+
+// Check all files now
+func (self *AssetWatcher) Poll() {
+	tm := GetTextureManager()
+	for name := range tm.Textures {
+		if self.changed(name) {
+			self.report(name, tm.ReloadTexture(name))
+		}
+	}
+
+	sm := GetShaderManager()
+	for filename := range sm.Shaders {
+		if self.changed(filename) {
+			self.report(filename, sm.ReloadShader(filename))
+		}
+	}
+}
+
+// First time a file is seen its time is only remembered. Names that
+// aren't files, like those of created textures, never change.
+func (self *AssetWatcher) changed(name string) bool {
+	info, err := GetVFS().Stat(name)
+	if err != nil {
+		return false
+	}
+
+	last, seen := self.modTimes[name]
+	self.modTimes[name] = info.ModTime()
+	return seen && !info.ModTime().Equal(last)
+}
+
+func (self *AssetWatcher) report(name string, err error) {
+	if err == nil {
+		return
+	}
+	if self.OnError != nil {
+		self.OnError(name, err)
+	} else {
+		log.Println("Reloading", name, "failed:", err)
+	}
+}
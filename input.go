@@ -0,0 +1,460 @@
+package glutils
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/banthar/Go-SDL/sdl"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Kinds of input bindings
+const (
+	BIND_KEY   = 1
+	BIND_MOUSE = 2
+)
+
+// Modifier groups, left and right keys are not told apart
+const (
+	MOD_SHIFT = 1
+	MOD_CTRL  = 2
+	MOD_ALT   = 4
+	MOD_META  = 8
+)
+
+var modNames = []struct {
+	mod  int
+	name string
+	mask uint32
+}{
+	{MOD_SHIFT, "shift", uint32(sdl.KMOD_SHIFT)},
+	{MOD_CTRL, "ctrl", uint32(sdl.KMOD_CTRL)},
+	{MOD_ALT, "alt", uint32(sdl.KMOD_ALT)},
+	{MOD_META, "meta", uint32(sdl.KMOD_META)},
+}
+
+// Single key or mouse button, with modifiers that must be held down
+// when it's pressed
+type Binding struct {
+	Kind int
+	// Key sym or mouse button number
+	Code int
+	Mods int
+}
+
+func KeyBinding(key uint32, mods int) Binding {
+	return Binding{BIND_KEY, int(key), mods}
+}
+
+func MouseBinding(button int, mods int) Binding {
+	return Binding{BIND_MOUSE, button, mods}
+}
+
+// Text form used in config files, like "key:s+ctrl" or "mouse:1"
+func (self Binding) String() string {
+	var ret string
+	if self.Kind == BIND_MOUSE {
+		ret = "mouse:" + strconv.Itoa(self.Code)
+	} else {
+		ret = "key:" + keyName(self.Code)
+	}
+
+	for _, m := range modNames {
+		if self.Mods&m.mod != 0 {
+			ret += "+" + m.name
+		}
+	}
+	return ret
+}
+
+func ParseBinding(text string) (Binding, error) {
+	ret := Binding{}
+	parts := strings.Split(text, "+")
+
+	kind := strings.SplitN(parts[0], ":", 2)
+	if len(kind) != 2 {
+		return ret, errors.New("Bad binding " + text)
+	}
+
+	switch kind[0] {
+	case "key":
+		ret.Kind = BIND_KEY
+		code, found := keyCode(kind[1])
+		if !found {
+			return ret, errors.New("Unknown key in binding " + text)
+		}
+		ret.Code = code
+	case "mouse":
+		ret.Kind = BIND_MOUSE
+		code, err := strconv.Atoi(kind[1])
+		if err != nil {
+			return ret, errors.New("Bad mouse button in binding " + text)
+		}
+		ret.Code = code
+	default:
+		return ret, errors.New("Bad binding " + text)
+	}
+
+	for _, name := range parts[1:] {
+		found := false
+		for _, m := range modNames {
+			if m.name == name {
+				ret.Mods |= m.mod
+				found = true
+			}
+		}
+		if !found {
+			return ret, errors.New("Unknown modifier in binding " + text)
+		}
+	}
+	return ret, nil
+}
+
+// Key names as SDL_GetKeyName gives them. SDL fills its own table on
+// video init only, so it can't be used for bindings loaded before Setup
+// or with HeadlessPlatform. Keys with ':' or '+' in the name would clash
+// with binding syntax and are written as numbers, same as unnamed ones.
+var keyNames = buildKeyNames()
+
+var keyCodes = buildKeyCodes(keyNames)
+
+func buildKeyNames() map[int]string {
+	ret := map[int]string{
+		sdl.K_BACKSPACE:   "backspace",
+		sdl.K_TAB:         "tab",
+		sdl.K_CLEAR:       "clear",
+		sdl.K_RETURN:      "return",
+		sdl.K_PAUSE:       "pause",
+		sdl.K_ESCAPE:      "escape",
+		sdl.K_SPACE:       "space",
+		sdl.K_DELETE:      "delete",
+		sdl.K_KP_PERIOD:   "[.]",
+		sdl.K_KP_DIVIDE:   "[/]",
+		sdl.K_KP_MULTIPLY: "[*]",
+		sdl.K_KP_MINUS:    "[-]",
+		sdl.K_KP_ENTER:    "enter",
+		sdl.K_KP_EQUALS:   "equals",
+		sdl.K_UP:          "up",
+		sdl.K_DOWN:        "down",
+		sdl.K_RIGHT:       "right",
+		sdl.K_LEFT:        "left",
+		sdl.K_INSERT:      "insert",
+		sdl.K_HOME:        "home",
+		sdl.K_END:         "end",
+		sdl.K_PAGEUP:      "page up",
+		sdl.K_PAGEDOWN:    "page down",
+		sdl.K_NUMLOCK:     "numlock",
+		sdl.K_CAPSLOCK:    "caps lock",
+		sdl.K_SCROLLOCK:   "scroll lock",
+		sdl.K_RSHIFT:      "right shift",
+		sdl.K_LSHIFT:      "left shift",
+		sdl.K_RCTRL:       "right ctrl",
+		sdl.K_LCTRL:       "left ctrl",
+		sdl.K_RALT:        "right alt",
+		sdl.K_LALT:        "left alt",
+		sdl.K_RMETA:       "right meta",
+		sdl.K_LMETA:       "left meta",
+		sdl.K_LSUPER:      "left super",
+		sdl.K_RSUPER:      "right super",
+		sdl.K_MODE:        "alt gr",
+		sdl.K_COMPOSE:     "compose",
+		sdl.K_HELP:        "help",
+		sdl.K_PRINT:       "print screen",
+		sdl.K_SYSREQ:      "sys req",
+		sdl.K_BREAK:       "break",
+		sdl.K_MENU:        "menu",
+		sdl.K_POWER:       "power",
+		sdl.K_EURO:        "euro",
+		sdl.K_UNDO:        "undo"}
+
+	// Printable keys are named by their character. Digits are left out,
+	// plain numbers are key codes.
+	for _, c := range "!\"#$&'()*,-./;<=>?@[\\]^_`abcdefghijklmnopqrstuvwxyz" {
+		ret[int(c)] = string(c)
+	}
+	for i := 0; i <= 95; i++ {
+		ret[sdl.K_WORLD_0+i] = "world " + strconv.Itoa(i)
+	}
+	for i := 0; i <= 9; i++ {
+		ret[sdl.K_KP0+i] = "[" + strconv.Itoa(i) + "]"
+	}
+	for i := 0; i < 15; i++ {
+		ret[sdl.K_F1+i] = "f" + strconv.Itoa(i+1)
+	}
+	return ret
+}
+
+func buildKeyCodes(names map[int]string) map[string]int {
+	ret := map[string]int{}
+	for code, name := range names {
+		ret[name] = code
+	}
+	return ret
+}
+
+func keyName(code int) string {
+	if name, found := keyNames[code]; found {
+		return name
+	}
+	return strconv.Itoa(code)
+}
+
+// Accepts SDL key names as well as plain key numbers
+func keyCode(name string) (int, bool) {
+	if code, err := strconv.Atoi(name); err == nil {
+		return code, true
+	}
+
+	code, found := keyCodes[name]
+	return code, found
+}
+
+// Convert SDL modifier state into MOD_ flags
+func modsFromSdl(mod uint32) (ret int) {
+	for _, m := range modNames {
+		if mod&m.mask != 0 {
+			ret |= m.mod
+		}
+	}
+	return
+}
+
+// Axis built from two actions, -1 when negative one is held, 1 for
+// positive one, 0 for both or none
+type InputAxis struct {
+	Negative string
+	Positive string
+}
+
+type boundInput struct {
+	action  string
+	binding Binding
+}
+
+type actionState struct {
+	down     int
+	pressed  bool
+	released bool
+}
+
+// Named actions bound to keys and mouse buttons. It's fed with events
+// by AppStateManager, Pressed and Released stay set for the whole frame.
+// In fixed step mode they stay set until one step has seen them.
+type InputMap struct {
+	Bindings map[string][]Binding
+	Axes     map[string]InputAxis
+
+	actions  map[string]*actionState
+	downKeys map[boundInput]bool
+	mods     int
+}
+
+func NewInputMap() *InputMap {
+	return &InputMap{
+		Bindings: map[string][]Binding{},
+		Axes:     map[string]InputAxis{},
+		actions:  map[string]*actionState{},
+		downKeys: map[boundInput]bool{}}
+}
+
+func (self *InputMap) Bind(action string, bindings ...Binding) {
+	self.Bindings[action] = append(self.Bindings[action], bindings...)
+}
+
+func (self *InputMap) BindKey(action string, key uint32, mods int) {
+	self.Bind(action, KeyBinding(key, mods))
+}
+
+func (self *InputMap) BindMouse(action string, button int, mods int) {
+	self.Bind(action, MouseBinding(button, mods))
+}
+
+func (self *InputMap) Unbind(action string) {
+	delete(self.Bindings, action)
+	delete(self.actions, action)
+}
+
+func (self *InputMap) BindAxis(axis, negative, positive string) {
+	self.Axes[axis] = InputAxis{negative, positive}
+}
+
+func (self *InputMap) getState(action string) *actionState {
+	state := self.actions[action]
+	if state == nil {
+		state = &actionState{}
+		self.actions[action] = state
+	}
+	return state
+}
+
+// Action went down during this frame
+func (self *InputMap) Pressed(action string) bool {
+	state := self.actions[action]
+	return state != nil && state.pressed
+}
+
+func (self *InputMap) Held(action string) bool {
+	state := self.actions[action]
+	return state != nil && state.down > 0
+}
+
+// Action went up during this frame
+func (self *InputMap) Released(action string) bool {
+	state := self.actions[action]
+	return state != nil && state.released
+}
+
+func (self *InputMap) Axis(name string) (ret float32) {
+	axis, found := self.Axes[name]
+	if !found {
+		return 0
+	}
+
+	if self.Held(axis.Negative) {
+		ret -= 1
+	}
+	if self.Held(axis.Positive) {
+		ret += 1
+	}
+	return
+}
+
+func (self *InputMap) HandleEvent(event sdl.Event) {
+	switch event.(type) {
+	case *sdl.KeyboardEvent:
+		kevent := event.(*sdl.KeyboardEvent)
+		self.mods = modsFromSdl(kevent.Keysym.Mod)
+		self.handleInput(BIND_KEY, int(kevent.Keysym.Sym), kevent.State == 1)
+		break
+	case *sdl.MouseButtonEvent:
+		mevent := event.(*sdl.MouseButtonEvent)
+		self.handleInput(BIND_MOUSE, int(mevent.Button), mevent.State == 1)
+		break
+	}
+}
+
+func (self *InputMap) handleInput(kind, code int, down bool) {
+	for action, bindings := range self.Bindings {
+		for _, b := range bindings {
+			if b.Kind != kind || b.Code != code {
+				continue
+			}
+			bound := boundInput{action, b}
+
+			if down {
+				// Modifiers matter only when going down, they
+				// may be let go before the key itself
+				if b.Mods&self.mods != b.Mods || self.downKeys[bound] {
+					continue
+				}
+				self.downKeys[bound] = true
+
+				state := self.getState(action)
+				if state.down == 0 {
+					state.pressed = true
+				}
+				state.down++
+			} else {
+				if !self.downKeys[bound] {
+					continue
+				}
+				delete(self.downKeys, bound)
+
+				state := self.getState(action)
+				state.down--
+				if state.down == 0 {
+					state.released = true
+				}
+			}
+		}
+	}
+}
+
+// Clear pressed and released flags, called after frame is processed or
+// after fixed step
+func (self *InputMap) EndFrame() {
+	for _, state := range self.actions {
+		state.pressed = false
+		state.released = false
+	}
+}
+
+type inputMapConfig struct {
+	Actions map[string][]string
+	Axes    map[string][2]string
+}
+
+// Replace bindings with ones read from JSON config
+func (self *InputMap) Load(r io.Reader) error {
+	config := inputMapConfig{}
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return err
+	}
+
+	bindings := map[string][]Binding{}
+	for action, texts := range config.Actions {
+		for _, text := range texts {
+			b, err := ParseBinding(text)
+			if err != nil {
+				return err
+			}
+			bindings[action] = append(bindings[action], b)
+		}
+	}
+
+	self.Bindings = bindings
+	self.Axes = map[string]InputAxis{}
+	for name, actions := range config.Axes {
+		self.BindAxis(name, actions[0], actions[1])
+	}
+
+	self.actions = map[string]*actionState{}
+	self.downKeys = map[boundInput]bool{}
+	return nil
+}
+
+func (self *InputMap) Save(w io.Writer) error {
+	config := inputMapConfig{
+		map[string][]string{},
+		map[string][2]string{}}
+
+	for action, bindings := range self.Bindings {
+		texts := []string{}
+		for _, b := range bindings {
+			texts = append(texts, b.String())
+		}
+		config.Actions[action] = texts
+	}
+	for name, axis := range self.Axes {
+		config.Axes[name] = [2]string{axis.Negative, axis.Positive}
+	}
+
+	data, err := json.MarshalIndent(&config, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (self *InputMap) LoadFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return self.Load(f)
+}
+
+func (self *InputMap) SaveFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return self.Save(f)
+}
@@ -0,0 +1,299 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+)
+
+// Points p where Normal·p + D = 0, Normal is normalised
+type Plane struct {
+	Normal v.Vector3f
+	D      float32
+}
+
+// Plane through point
+func NewPlane(normal, point v.Vector3f) Plane {
+	normal = vecNormalize(normal)
+	return Plane{normal, -vecDot(normal, point)}
+}
+
+// Signed distance, positive on the side normal points to
+func (self Plane) Distance(point v.Vector3f) float32 {
+	return vecDot(self.Normal, point) + self.D
+}
+
+// Axis aligned box
+type AABB struct {
+	Min, Max v.Vector3f
+}
+
+func (self AABB) Centre() v.Vector3f {
+	return vecScale(vecAdd(self.Min, self.Max), 0.5)
+}
+
+// Half of box size along each axis
+func (self AABB) Extents() v.Vector3f {
+	return vecScale(vecSub(self.Max, self.Min), 0.5)
+}
+
+// Oriented box, Axes are normalised and perpendicular
+type OBB struct {
+	Centre   v.Vector3f
+	Axes     [3]v.Vector3f
+	HalfSize v.Vector3f
+}
+
+// Where ray hits a shape. Normal points outside of the shape, for
+// planes and triangles it faces the ray origin.
+type RayHit struct {
+	Distance float32
+	Point    v.Vector3f
+	Normal   v.Vector3f
+}
+
+func (self Ray) hit(t float32, normal v.Vector3f) RayHit {
+	return RayHit{t, self.At(t), normal}
+}
+
+func (self Ray) HitPlane(plane Plane) (RayHit, bool) {
+	denom := vecDot(plane.Normal, self.Direction)
+	if denom == 0 {
+		return RayHit{}, false
+	}
+
+	t := -plane.Distance(self.Origin) / denom
+	if t < 0 {
+		return RayHit{}, false
+	}
+
+	normal := plane.Normal
+	if denom > 0 {
+		normal = vecScale(normal, -1)
+	}
+	return self.hit(t, normal), true
+}
+
+// Ray starting inside the sphere hits it from the inside
+func (self Ray) HitSphere(centre v.Vector3f, radius float32) (RayHit, bool) {
+	oc := vecSub(self.Origin, centre)
+	b := vecDot(oc, self.Direction)
+	c := vecDot(oc, oc) - radius*radius
+
+	disc := b*b - c
+	if disc < 0 {
+		return RayHit{}, false
+	}
+
+	sq := float32(math.Sqrt(float64(disc)))
+	t := -b - sq
+	if t < 0 {
+		t = -b + sq
+	}
+	if t < 0 {
+		return RayHit{}, false
+	}
+
+	point := self.At(t)
+	return RayHit{t, point, vecNormalize(vecSub(point, centre))}, true
+}
+
+// Slab test, ray starting inside the box hits it from the inside
+func (self Ray) HitAABB(box AABB) (RayHit, bool) {
+	origin := [3]float32{self.Origin.X, self.Origin.Y, self.Origin.Z}
+	dir := [3]float32{self.Direction.X, self.Direction.Y, self.Direction.Z}
+	min := [3]float32{box.Min.X, box.Min.Y, box.Min.Z}
+	max := [3]float32{box.Max.X, box.Max.Y, box.Max.Z}
+
+	tNear, tFar := float32(math.Inf(-1)), float32(math.Inf(1))
+	nearAxis, farAxis := 0, 0
+	var nearSign, farSign float32
+
+	for i := 0; i < 3; i++ {
+		if dir[i] == 0 {
+			if origin[i] < min[i] || origin[i] > max[i] {
+				return RayHit{}, false
+			}
+			continue
+		}
+
+		t1 := (min[i] - origin[i]) / dir[i]
+		t2 := (max[i] - origin[i]) / dir[i]
+		// Normal of the face ray enters through
+		sign := float32(-1)
+		if t1 > t2 {
+			t1, t2 = t2, t1
+			sign = 1
+		}
+
+		if t1 > tNear {
+			tNear, nearAxis, nearSign = t1, i, sign
+		}
+		if t2 < tFar {
+			tFar, farAxis, farSign = t2, i, -sign
+		}
+		if tNear > tFar || tFar < 0 {
+			return RayHit{}, false
+		}
+	}
+
+	t, axis, sign := tNear, nearAxis, nearSign
+	if t < 0 {
+		t, axis, sign = tFar, farAxis, farSign
+	}
+	if math.IsInf(float64(t), 0) {
+		// Zero direction
+		return RayHit{}, false
+	}
+
+	var normal [3]float32
+	normal[axis] = sign
+	return self.hit(t, v.Vector3f{normal[0], normal[1], normal[2]}), true
+}
+
+// Tested in box space, hit is given back in world space
+func (self Ray) HitOBB(box OBB) (RayHit, bool) {
+	rel := vecSub(self.Origin, box.Centre)
+	local := Ray{
+		v.Vector3f{vecDot(rel, box.Axes[0]), vecDot(rel, box.Axes[1]), vecDot(rel, box.Axes[2])},
+		v.Vector3f{vecDot(self.Direction, box.Axes[0]),
+			vecDot(self.Direction, box.Axes[1]),
+			vecDot(self.Direction, box.Axes[2])}}
+
+	hit, ok := local.HitAABB(AABB{vecScale(box.HalfSize, -1), box.HalfSize})
+	if !ok {
+		return RayHit{}, false
+	}
+
+	normal := vecAdd(vecAdd(vecScale(box.Axes[0], hit.Normal.X),
+		vecScale(box.Axes[1], hit.Normal.Y)),
+		vecScale(box.Axes[2], hit.Normal.Z))
+	return self.hit(hit.Distance, normal), true
+}
+
+// Both sides of triangle are hit
+func (self Ray) HitTriangle(a, b, c v.Vector3f) (RayHit, bool) {
+	const epsilon = 1e-7
+
+	edge1 := vecSub(b, a)
+	edge2 := vecSub(c, a)
+	p := vecCross(self.Direction, edge2)
+	det := vecDot(edge1, p)
+	if det > -epsilon && det < epsilon {
+		return RayHit{}, false
+	}
+	invDet := 1 / det
+
+	s := vecSub(self.Origin, a)
+	u := vecDot(s, p) * invDet
+	if u < 0 || u > 1 {
+		return RayHit{}, false
+	}
+
+	q := vecCross(s, edge1)
+	w := vecDot(self.Direction, q) * invDet
+	if w < 0 || u+w > 1 {
+		return RayHit{}, false
+	}
+
+	t := vecDot(edge2, q) * invDet
+	if t < 0 {
+		return RayHit{}, false
+	}
+
+	normal := vecNormalize(vecCross(edge1, edge2))
+	if vecDot(normal, self.Direction) > 0 {
+		normal = vecScale(normal, -1)
+	}
+	return self.hit(t, normal), true
+}
+
+// Capsule is segment a-b swept by sphere of radius
+func (self Ray) HitCapsule(a, b v.Vector3f, radius float32) (RayHit, bool) {
+	axis := vecSub(b, a)
+	axisLen := vecLength(axis)
+	if axisLen == 0 {
+		return self.HitSphere(a, radius)
+	}
+	axis = vecScale(axis, 1/axisLen)
+
+	best := RayHit{Distance: float32(math.Inf(1))}
+	found := false
+
+	// Side of infinite cylinder, clipped to the segment
+	oc := vecSub(self.Origin, a)
+	dPerp := vecSub(self.Direction, vecScale(axis, vecDot(self.Direction, axis)))
+	oPerp := vecSub(oc, vecScale(axis, vecDot(oc, axis)))
+	qa := vecDot(dPerp, dPerp)
+	qb := vecDot(oPerp, dPerp)
+	qc := vecDot(oPerp, oPerp) - radius*radius
+	if disc := qb*qb - qa*qc; qa != 0 && disc >= 0 {
+		sq := float32(math.Sqrt(float64(disc)))
+		for _, t := range [2]float32{(-qb - sq) / qa, (-qb + sq) / qa} {
+			if t < 0 || t >= best.Distance {
+				continue
+			}
+			point := self.At(t)
+			along := vecDot(vecSub(point, a), axis)
+			if along < 0 || along > axisLen {
+				continue
+			}
+			centre := vecAdd(a, vecScale(axis, along))
+			best = RayHit{t, point, vecNormalize(vecSub(point, centre))}
+			found = true
+			break
+		}
+	}
+
+	// Caps
+	for _, centre := range [2]v.Vector3f{a, b} {
+		if hit, ok := self.HitSphere(centre, radius); ok && hit.Distance < best.Distance {
+			best = hit
+			found = true
+		}
+	}
+
+	if !found {
+		return RayHit{}, false
+	}
+	return best, true
+}
+
+// Closest hit of indexed triangle mesh, using arrays kept in memory.
+// Transform places the mesh in the world, nil means identity. Also
+// returns index of the triangle that was hit.
+func (self Ray) HitMesh(mesh *MeshBuffer, transform *v.Matrix4) (hit RayHit, triangle int, ok bool) {
+	vertexArray, indiceArray := mesh.GetArrays()
+	stride := mesh.CalcVertexSize()
+	vertexCount := len(vertexArray) / stride
+
+	positions := make([]v.Vector3f, vertexCount)
+	for i := range positions {
+		data := vertexArray[i*stride:]
+		p := v.Vector3f{
+			math.Float32frombits(byteOrder.Uint32(data[0:])),
+			math.Float32frombits(byteOrder.Uint32(data[4:])),
+			math.Float32frombits(byteOrder.Uint32(data[8:]))}
+		if transform != nil {
+			p.X, p.Y, p.Z, _ = matrixTransform(transform, p.X, p.Y, p.Z, 1)
+		}
+		positions[i] = p
+	}
+
+	hit.Distance = float32(math.Inf(1))
+	for i := 0; i+6 <= len(indiceArray); i += 6 {
+		ia := int(byteOrder.Uint16(indiceArray[i:]))
+		ib := int(byteOrder.Uint16(indiceArray[i+2:]))
+		ic := int(byteOrder.Uint16(indiceArray[i+4:]))
+		if ia >= vertexCount || ib >= vertexCount || ic >= vertexCount {
+			continue
+		}
+
+		if h, found := self.HitTriangle(positions[ia], positions[ib], positions[ic]); found && h.Distance < hit.Distance {
+			hit, triangle, ok = h, i/6, true
+		}
+	}
+	if !ok {
+		hit = RayHit{}
+	}
+	return
+}
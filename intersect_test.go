@@ -0,0 +1,216 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+	"testing"
+)
+
+type hitCase struct {
+	name string
+	ray  Ray
+	ok   bool
+	want RayHit
+}
+
+func checkHit(t *testing.T, name string, got RayHit, ok bool, wantOK bool, want RayHit) {
+	t.Helper()
+	if ok != wantOK {
+		t.Errorf("%s: hit=%v, want %v (%+v)", name, ok, wantOK, got)
+		return
+	}
+	if !ok {
+		if got != (RayHit{}) {
+			t.Errorf("%s: miss returned %+v", name, got)
+		}
+		return
+	}
+	if !nearlyEqual(got.Distance, want.Distance, testEpsilon) ||
+		!vecNearlyEqual(got.Point, want.Point, testEpsilon) ||
+		!vecNearlyEqual(got.Normal, want.Normal, testEpsilon) {
+		t.Errorf("%s: got %+v, want %+v", name, got, want)
+	}
+}
+
+func testRay(ox, oy, oz, dx, dy, dz float32) Ray {
+	return NewRay(v.Vector3f{ox, oy, oz}, v.Vector3f{dx, dy, dz})
+}
+
+func TestHitPlane(t *testing.T) {
+	plane := NewPlane(v.Vector3f{0, 2, 0}, v.Vector3f{0, 1, 0})
+
+	cases := []hitCase{
+		{"from above", testRay(3, 5, 0, 0, -1, 0), true, RayHit{4, v.Vector3f{3, 1, 0}, v.Vector3f{0, 1, 0}}},
+		{"from below", testRay(0, -3, 0, 0, 1, 0), true, RayHit{4, v.Vector3f{0, 1, 0}, v.Vector3f{0, -1, 0}}},
+		{"slanted", testRay(0, 3, 0, 1, -1, 0), true, RayHit{float32(2 * math.Sqrt2), v.Vector3f{2, 1, 0}, v.Vector3f{0, 1, 0}}},
+		{"starting on plane", testRay(0, 1, 0, 0, -1, 0), true, RayHit{0, v.Vector3f{0, 1, 0}, v.Vector3f{0, 1, 0}}},
+		{"pointing away", testRay(0, 5, 0, 0, 1, 0), false, RayHit{}},
+		{"parallel", testRay(0, 5, 0, 1, 0, 0), false, RayHit{}},
+		{"parallel in plane", testRay(0, 1, 0, 0, 0, 1), false, RayHit{}},
+	}
+
+	for _, c := range cases {
+		got, ok := c.ray.HitPlane(plane)
+		checkHit(t, c.name, got, ok, c.ok, c.want)
+	}
+}
+
+func TestHitSphere(t *testing.T) {
+	centre := v.Vector3f{0, 0, -10}
+
+	cases := []hitCase{
+		{"head on", testRay(0, 0, 0, 0, 0, -1), true, RayHit{8, v.Vector3f{0, 0, -8}, v.Vector3f{0, 0, 1}}},
+		{"grazing", testRay(2, 0, 0, 0, 0, -1), true, RayHit{10, v.Vector3f{2, 0, -10}, v.Vector3f{1, 0, 0}}},
+		{"inside", testRay(0, 0, -10, 0, 0, -1), true, RayHit{2, v.Vector3f{0, 0, -12}, v.Vector3f{0, 0, -1}}},
+		{"miss", testRay(0, 2.5, 0, 0, 0, -1), false, RayHit{}},
+		{"behind", testRay(0, 0, 0, 0, 0, 1), false, RayHit{}},
+	}
+
+	for _, c := range cases {
+		got, ok := c.ray.HitSphere(centre, 2)
+		checkHit(t, c.name, got, ok, c.ok, c.want)
+	}
+}
+
+func TestHitAABB(t *testing.T) {
+	box := AABB{v.Vector3f{-1, -1, -1}, v.Vector3f{1, 2, 1}}
+
+	cases := []hitCase{
+		{"front face", testRay(0, 0, 5, 0, 0, -1), true, RayHit{4, v.Vector3f{0, 0, 1}, v.Vector3f{0, 0, 1}}},
+		{"left face", testRay(-5, 1, 0, 1, 0, 0), true, RayHit{4, v.Vector3f{-1, 1, 0}, v.Vector3f{-1, 0, 0}}},
+		{"top face", testRay(0, 7, 0, 0, -1, 0), true, RayHit{5, v.Vector3f{0, 2, 0}, v.Vector3f{0, 1, 0}}},
+		{"grazing edge", testRay(1, 0, 5, 0, 0, -1), true, RayHit{4, v.Vector3f{1, 0, 1}, v.Vector3f{0, 0, 1}}},
+		{"inside", testRay(0, 0, 0, 1, 0, 0), true, RayHit{1, v.Vector3f{1, 0, 0}, v.Vector3f{1, 0, 0}}},
+		{"inside upwards", testRay(0, 0, 0, 0, 1, 0), true, RayHit{2, v.Vector3f{0, 2, 0}, v.Vector3f{0, 1, 0}}},
+		{"miss", testRay(0, 3, 5, 0, 0, -1), false, RayHit{}},
+		{"miss diagonal", testRay(-5, 0, 5, 1, 0, 1), false, RayHit{}},
+		{"parallel outside slab", testRay(2, -5, 0, 0, 1, 0), false, RayHit{}},
+		{"behind", testRay(0, 0, 5, 0, 0, 1), false, RayHit{}},
+	}
+
+	for _, c := range cases {
+		got, ok := c.ray.HitAABB(box)
+		checkHit(t, c.name, got, ok, c.ok, c.want)
+	}
+}
+
+func TestHitOBB(t *testing.T) {
+	// Rotated by 45 degrees around Z
+	s := float32(math.Sqrt(0.5))
+	box := OBB{
+		Centre:   v.Vector3f{10, 0, 0},
+		Axes:     [3]v.Vector3f{{s, s, 0}, {-s, s, 0}, {0, 0, 1}},
+		HalfSize: v.Vector3f{1, 1, 1}}
+
+	cases := []hitCase{
+		{"top face", testRay(10, 0, 5, 0, 0, -1), true, RayHit{4, v.Vector3f{10, 0, 1}, v.Vector3f{0, 0, 1}}},
+		{"rotated face", testRay(10-5*s, -5*s, 0, 1, 1, 0), true, RayHit{4, v.Vector3f{10 - s, -s, 0}, v.Vector3f{-s, -s, 0}}},
+		{"inside", testRay(10, 0, 0, 0, 0, 1), true, RayHit{1, v.Vector3f{10, 0, 1}, v.Vector3f{0, 0, 1}}},
+		// Inside the unrotated box, outside the rotated one
+		{"miss corner", testRay(10.9, 0.9, 5, 0, 0, -1), false, RayHit{}},
+		{"behind", testRay(10, 0, 5, 0, 0, 1), false, RayHit{}},
+	}
+
+	for _, c := range cases {
+		got, ok := c.ray.HitOBB(box)
+		checkHit(t, c.name, got, ok, c.ok, c.want)
+	}
+}
+
+func TestHitTriangle(t *testing.T) {
+	a, b, c := v.Vector3f{0, 0, 0}, v.Vector3f{1, 0, 0}, v.Vector3f{0, 1, 0}
+
+	cases := []hitCase{
+		{"front", testRay(0.25, 0.25, 1, 0, 0, -1), true, RayHit{1, v.Vector3f{0.25, 0.25, 0}, v.Vector3f{0, 0, 1}}},
+		{"back", testRay(0.25, 0.25, -2, 0, 0, 1), true, RayHit{2, v.Vector3f{0.25, 0.25, 0}, v.Vector3f{0, 0, -1}}},
+		{"grazing edge", testRay(0.5, 0, 1, 0, 0, -1), true, RayHit{1, v.Vector3f{0.5, 0, 0}, v.Vector3f{0, 0, 1}}},
+		{"vertex", testRay(0, 1, 1, 0, 0, -1), true, RayHit{1, v.Vector3f{0, 1, 0}, v.Vector3f{0, 0, 1}}},
+		{"miss past hypotenuse", testRay(0.6, 0.6, 1, 0, 0, -1), false, RayHit{}},
+		{"miss outside", testRay(-0.1, 0.5, 1, 0, 0, -1), false, RayHit{}},
+		{"parallel", testRay(0.25, 0.25, 0, 1, 0, 0), false, RayHit{}},
+		{"behind", testRay(0.25, 0.25, 1, 0, 0, 1), false, RayHit{}},
+	}
+
+	for _, tc := range cases {
+		got, ok := tc.ray.HitTriangle(a, b, c)
+		checkHit(t, tc.name, got, ok, tc.ok, tc.want)
+	}
+}
+
+func TestHitCapsule(t *testing.T) {
+	a, b := v.Vector3f{0, 0, 0}, v.Vector3f{0, 5, 0}
+	side := float32(math.Sqrt(0.75))
+
+	cases := []hitCase{
+		{"side", testRay(5, 2, 0, -1, 0, 0), true, RayHit{4, v.Vector3f{1, 2, 0}, v.Vector3f{1, 0, 0}}},
+		{"top cap", testRay(0, 10, 0, 0, -1, 0), true, RayHit{4, v.Vector3f{0, 6, 0}, v.Vector3f{0, 1, 0}}},
+		{"bottom cap", testRay(0, -10, 0, 0, 1, 0), true, RayHit{9, v.Vector3f{0, -1, 0}, v.Vector3f{0, -1, 0}}},
+		{"parallel to axis", testRay(0.5, -10, 0, 0, 1, 0), true, RayHit{10 - side, v.Vector3f{0.5, -side, 0}, v.Vector3f{0.5, -side, 0}}},
+		{"grazing side", testRay(1, 2, 5, 0, 0, -1), true, RayHit{5, v.Vector3f{1, 2, 0}, v.Vector3f{1, 0, 0}}},
+		{"cap beyond side", testRay(5, 5.5, 0, -1, 0, 0), true, RayHit{5 - side, v.Vector3f{side, 5.5, 0}, v.Vector3f{side, 0.5, 0}}},
+		{"inside", testRay(0, 2, 0, 1, 0, 0), true, RayHit{1, v.Vector3f{1, 2, 0}, v.Vector3f{1, 0, 0}}},
+		{"miss", testRay(5, 7, 0, -1, 0, 0), false, RayHit{}},
+		{"miss parallel", testRay(2, -10, 0, 0, 1, 0), false, RayHit{}},
+		{"behind", testRay(5, 2, 0, 1, 0, 0), false, RayHit{}},
+	}
+
+	for _, c := range cases {
+		got, ok := c.ray.HitCapsule(a, b, 1)
+		checkHit(t, c.name, got, ok, c.ok, c.want)
+	}
+
+	// Zero length capsule is a sphere
+	got, ok := testRay(0, 0, 5, 0, 0, -1).HitCapsule(a, a, 1)
+	checkHit(t, "degenerate", got, ok, true, RayHit{4, v.Vector3f{0, 0, 1}, v.Vector3f{0, 0, 1}})
+}
+
+// Two 2x1 quads, at z=0 and z=-1, the far one added first
+func newTestHitMesh() *MeshBuffer {
+	builder := NewMeshBuilder()
+	for _, z := range []float32{0, -1} {
+		for _, p := range [][2]float32{{0, 0}, {2, 0}, {2, 1}, {0, 1}} {
+			builder.StartVertex()
+			builder.AddPosition(p[0], p[1], z)
+		}
+	}
+	builder.AddIndice3(4, 5, 6)
+	builder.AddIndice3(4, 6, 7)
+	builder.AddIndice3(0, 1, 2)
+	builder.AddIndice3(0, 2, 3)
+	// Broken indices are skipped
+	builder.AddIndice3(0, 1, 99)
+
+	mesh := NewMeshBuffer(0, 0, RENDER_POLYGONS, 0)
+	builder.Finalize(false, mesh)
+	return mesh
+}
+
+func TestHitMesh(t *testing.T) {
+	mesh := newTestHitMesh()
+	moved := translationMatrix(0, 0, 3)
+
+	cases := []struct {
+		hitCase
+		transform *v.Matrix4
+		triangle  int
+	}{
+		{hitCase{"closest triangle", testRay(1.5, 0.5, 5, 0, 0, -1), true,
+			RayHit{5, v.Vector3f{1.5, 0.5, 0}, v.Vector3f{0, 0, 1}}}, nil, 2},
+		{hitCase{"second triangle of quad", testRay(0.5, 0.5, 5, 0, 0, -1), true,
+			RayHit{5, v.Vector3f{0.5, 0.5, 0}, v.Vector3f{0, 0, 1}}}, nil, 3},
+		{hitCase{"from below", testRay(1.5, 0.5, -5, 0, 0, 1), true,
+			RayHit{4, v.Vector3f{1.5, 0.5, -1}, v.Vector3f{0, 0, -1}}}, nil, 0},
+		{hitCase{"transformed", testRay(1.5, 0.5, 5, 0, 0, -1), true,
+			RayHit{2, v.Vector3f{1.5, 0.5, 3}, v.Vector3f{0, 0, 1}}}, &moved, 2},
+		{hitCase{"miss", testRay(3, 0.5, 5, 0, 0, -1), false, RayHit{}}, nil, 0},
+		{hitCase{"parallel", testRay(-1, 0.5, 0, 1, 0, 0), false, RayHit{}}, nil, 0},
+	}
+
+	for _, c := range cases {
+		got, triangle, ok := c.ray.HitMesh(mesh, c.transform)
+		checkHit(t, c.name, got, ok, c.ok, c.want)
+		if ok && triangle != c.triangle {
+			t.Errorf("%s: triangle %d, want %d", c.name, triangle, c.triangle)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package glutils
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var FLAG_glthread *bool = flag.Bool("glthread", false, "panic when GL objects are used outside the main thread")
+
+// Goroutine that called Setup, 0 before that
+var mainGoroutine uint64
+
+// Result of closure passed to DispatchFuture
+type Future struct {
+	done  chan bool
+	value interface{}
+	err   error
+}
+
+// Block until the closure has run on the main thread. Must not be
+// called from the main thread itself, it would never return.
+func (self *Future) Wait() (interface{}, error) {
+	<-self.done
+	return self.value, self.err
+}
+
+func (self *Future) Done() bool {
+	select {
+	case <-self.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Closures posted from other goroutines, run by the main thread between
+// frames
+type dispatchQueue struct {
+	lock  sync.Mutex
+	funcs []func()
+}
+
+func (self *dispatchQueue) post(f func()) {
+	self.lock.Lock()
+	self.funcs = append(self.funcs, f)
+	self.lock.Unlock()
+}
+
+func (self *dispatchQueue) run() {
+	self.lock.Lock()
+	funcs := self.funcs
+	self.funcs = nil
+	self.lock.Unlock()
+
+	for _, f := range funcs {
+		f()
+	}
+}
+
+// Run f on the main thread between frames. Safe to call from any
+// goroutine.
+func (self *AppStateManager) Dispatch(f func()) {
+	self.dispatched.post(f)
+}
+
+// Like Dispatch, with result of f delivered through returned Future
+func (self *AppStateManager) DispatchFuture(f func() (interface{}, error)) *Future {
+	future := &Future{done: make(chan bool)}
+	self.dispatched.post(func() {
+		future.value, future.err = f()
+		close(future.done)
+	})
+	return future
+}
+
+// GL context belongs to the thread that created it, so the goroutine
+// running Setup has to stay on it
+func lockMainThread() {
+	runtime.LockOSThread()
+	mainGoroutine = goroutineId()
+}
+
+func goroutineId() uint64 {
+	var buf [64]byte
+	// Starts with "goroutine 42 [running]:"
+	fields := strings.Fields(string(buf[:runtime.Stack(buf[:], false)]))
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(fields[1], 10, 64)
+	return id
+}
+
+// Panic when called outside the main thread, checked with -glthread only
+func assertMainThread(what string) {
+	if !*FLAG_glthread || mainGoroutine == 0 {
+		return
+	}
+	if id := goroutineId(); id != mainGoroutine {
+		panic(fmt.Sprintf("%s called from goroutine %d, GL belongs to goroutine %d",
+			what, id, mainGoroutine))
+	}
+}
@@ -57,8 +57,11 @@ func (self *MeshBuffer) GetArrays() (vertexArray, indiceArray []uint8) {
 }
 
 func (self *MeshBuffer) AllocBuffers() {
+	assertMainThread("MeshBuffer.AllocBuffers")
+	defer checkGL("MeshBuffer.AllocBuffers")
 	if self.VertexBuffer == 0 {
 		self.VertexBuffer = gl.GenBuffer()
+		GetResourceRegistry().track(self, RESOURCE_BUFFER, "mesh", 0)
 	}
 	if self.IndiceBuffer == 0 {
 		self.IndiceBuffer = gl.GenBuffer()
@@ -70,18 +73,25 @@ func (self *MeshBuffer) HaveVBO() bool {
 }
 
 func (self *MeshBuffer) Destroy() {
+	assertMainThread("MeshBuffer.Destroy")
+	defer checkGL("MeshBuffer.Destroy")
 	if self.VertexBuffer != 0 {
 		self.VertexBuffer.Delete()
+		self.VertexBuffer = 0
 	}
 	if self.IndiceBuffer != 0 {
 		self.IndiceBuffer.Delete()
+		self.IndiceBuffer = 0
 	}
 
 	self.vertexArray = nil
 	self.indiceArray = nil
+	GetResourceRegistry().untrack(self)
 }
 
 func (self *MeshBuffer) CopyArraysToVBO() {
+	assertMainThread("MeshBuffer.CopyArraysToVBO")
+	defer checkGL("MeshBuffer.CopyArraysToVBO")
 	self.AllocBuffers()
 
 	vs := self.CalcVertexSize()
@@ -94,6 +104,8 @@ func (self *MeshBuffer) CopyArraysToVBO() {
 	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, 2*self.IndiceCount,
 		self.indiceArray, gl.STATIC_DRAW)
 	gl.BufferUnbind(gl.ELEMENT_ARRAY_BUFFER)
+
+	GetResourceRegistry().resize(self, vs*self.VertexCount+2*self.IndiceCount)
 }
 
 func (self *MeshBuffer) CalcVertexSize() int {
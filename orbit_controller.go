@@ -0,0 +1,162 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+)
+
+// Camera circling around target point, for editors and model viewers.
+// Movement made while grabbed keeps going after release and slows down
+// with Damping.
+type OrbitController struct {
+	Camera *Camera
+	Target v.Vector3f
+
+	Distance                 float32
+	MinDistance, MaxDistance float32
+
+	// Yaw around Y axis and pitch above horizontal plane, in radians
+	Yaw, Pitch         float32
+	MinPitch, MaxPitch float32
+	// Yaw isn't limited when they are equal
+	MinYaw, MaxYaw float32
+
+	// Speed left after one second is exp(-Damping)
+	Damping float32
+
+	grabbed            bool
+	yawStep, pitchStep float32
+	panStep            v.Vector3f
+	yawVel, pitchVel   float32
+	panVel             v.Vector3f
+}
+
+func NewOrbitController(camera *Camera) *OrbitController {
+	return &OrbitController{
+		Camera:      camera,
+		Distance:    10,
+		MinDistance: 0.1,
+		MaxDistance: 1000,
+		MinPitch:    -0.49 * math.Pi,
+		MaxPitch:    0.49 * math.Pi,
+		Damping:     4}
+}
+
+// Start of mouse drag, stops inertia
+func (self *OrbitController) Grab() {
+	self.grabbed = true
+	self.yawVel, self.pitchVel = 0, 0
+	self.panVel = v.Vector3f{}
+}
+
+// End of mouse drag, movement of last frame carries on
+func (self *OrbitController) Release() {
+	self.grabbed = false
+}
+
+func (self *OrbitController) RotateBy(deltaYaw, deltaPitch float32) {
+	self.rotate(deltaYaw, deltaPitch)
+	self.yawStep += deltaYaw
+	self.pitchStep += deltaPitch
+}
+
+// Move target along camera right and up vectors, in world units
+func (self *OrbitController) Pan(right, up float32) {
+	_, r, u := self.axes()
+	delta := vecAdd(vecScale(r, right), vecScale(u, up))
+	self.Target = vecAdd(self.Target, delta)
+	self.panStep = vecAdd(self.panStep, delta)
+}
+
+// Pan by mouse movement, so point at target distance follows the cursor
+func (self *OrbitController) PanPixels(dx, dy float32) {
+	// Ortho projection maps pixels to world units one to one
+	perPixel := float32(1)
+	if self.Camera.Fov != 0 {
+		halfFov := float64(self.Camera.Fov) * math.Pi / 360
+		perPixel = 2 * self.Distance * float32(math.Tan(halfFov)) / self.Camera.Viewport.Height
+	}
+	self.Pan(-dx*perPixel, dy*perPixel)
+}
+
+// Multiply distance by factor, within limits
+func (self *OrbitController) Zoom(factor float32) {
+	self.Distance = float32(math.Max(float64(self.MinDistance),
+		math.Min(float64(self.MaxDistance), float64(self.Distance*factor))))
+}
+
+// Target centre of box and move back so the whole box is visible.
+// Distance limits aren't applied.
+func (self *OrbitController) FrameBounds(box AABB) {
+	self.Target = box.Centre()
+	radius := vecLength(box.Extents())
+
+	if self.Camera.Fov == 0 {
+		self.Distance = radius * 2
+		return
+	}
+
+	halfFov := float64(self.Camera.Fov) * math.Pi / 360
+	halfFovX := math.Atan(math.Tan(halfFov) * float64(self.Camera.Viewport.Aspect))
+	self.Distance = radius / float32(math.Sin(math.Min(halfFov, halfFovX)))
+}
+
+// Apply inertia, to be called every frame
+func (self *OrbitController) Update(time_step float32) {
+	if time_step <= 0 {
+		return
+	}
+
+	if self.grabbed {
+		self.yawVel = self.yawStep / time_step
+		self.pitchVel = self.pitchStep / time_step
+		self.panVel = vecScale(self.panStep, 1/time_step)
+	} else {
+		self.rotate(self.yawVel*time_step, self.pitchVel*time_step)
+		self.Target = vecAdd(self.Target, vecScale(self.panVel, time_step))
+
+		k := float32(math.Exp(float64(-self.Damping * time_step)))
+		self.yawVel *= k
+		self.pitchVel *= k
+		self.panVel = vecScale(self.panVel, k)
+	}
+
+	self.yawStep, self.pitchStep = 0, 0
+	self.panStep = v.Vector3f{}
+}
+
+func (self *OrbitController) EyePosition() v.Vector3f {
+	forward, _, _ := self.axes()
+	return vecSub(self.Target, vecScale(forward, self.Distance))
+}
+
+func (self *OrbitController) SetupCamera() {
+	eye := self.EyePosition()
+	self.Camera.SetModelview(eye.X, eye.Y, eye.Z,
+		self.Target.X, self.Target.Y, self.Target.Z,
+		0, 1, 0)
+}
+
+func (self *OrbitController) rotate(deltaYaw, deltaPitch float32) {
+	self.Yaw += deltaYaw
+	if self.MinYaw < self.MaxYaw {
+		self.Yaw = float32(math.Max(float64(self.MinYaw), math.Min(float64(self.MaxYaw), float64(self.Yaw))))
+	} else {
+		self.Yaw = float32(math.Mod(float64(self.Yaw), 2*math.Pi))
+	}
+
+	self.Pitch = float32(math.Max(float64(self.MinPitch),
+		math.Min(float64(self.MaxPitch), float64(self.Pitch+deltaPitch))))
+}
+
+// View direction and camera right and up vectors
+func (self *OrbitController) axes() (forward, right, up v.Vector3f) {
+	cp := float32(math.Cos(float64(self.Pitch)))
+	forward = v.Vector3f{
+		-cp * float32(math.Sin(float64(self.Yaw))),
+		-float32(math.Sin(float64(self.Pitch))),
+		-cp * float32(math.Cos(float64(self.Yaw)))}
+	right = vecNormalize(vecCross(forward, v.Vector3f{0, 1, 0}))
+	up = vecCross(right, forward)
+	return
+}
@@ -0,0 +1,117 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+	"testing"
+)
+
+func TestOrbitFrameBounds(t *testing.T) {
+	box := AABB{v.Vector3f{-1, -1, -1}, v.Vector3f{3, 1, 1}}
+	radius := float32(math.Sqrt(6))
+
+	wide := testCameras()[0].cam
+	tall := NewCamera(&Viewport{Width: 300, Height: 600, Aspect: 0.5})
+	tall.SetFrustrumProjection(60, 1, 100)
+	ortho := testCameras()[2].cam
+
+	halfFovX := math.Atan(math.Tan(math.Pi/6) * 0.5)
+	cases := []struct {
+		name     string
+		cam      *Camera
+		distance float32
+	}{
+		// Vertical fov is the narrower one
+		{"wide", wide, radius / 0.5},
+		{"tall", tall, radius / float32(math.Sin(halfFovX))},
+		{"ortho", ortho, radius * 2},
+	}
+
+	for _, c := range cases {
+		orbit := NewOrbitController(c.cam)
+		orbit.Yaw, orbit.Pitch = 0.7, 0.3
+		orbit.MaxDistance = 1
+		orbit.FrameBounds(box)
+
+		if orbit.Target != (v.Vector3f{1, 0, 0}) {
+			t.Errorf("%s: target %v", c.name, orbit.Target)
+		}
+		if !nearlyEqual(orbit.Distance, c.distance, testEpsilon) {
+			t.Errorf("%s: distance %v, want %v", c.name, orbit.Distance, c.distance)
+		}
+	}
+
+	// Bounding sphere touches the narrower pair of side planes
+	for _, c := range cases[:2] {
+		orbit := NewOrbitController(c.cam)
+		orbit.Yaw, orbit.Pitch = 0.7, 0.3
+		orbit.FrameBounds(box)
+		c.cam.ModelviewMatrix = lookAtMatrix(orbit.EyePosition(), orbit.Target, v.Vector3f{0, 1, 0})
+
+		frustum := c.cam.Frustum()
+		closest := float32(math.Inf(1))
+		for _, plane := range frustum.Planes[:4] {
+			closest = float32(math.Min(float64(closest), float64(plane.Distance(orbit.Target))))
+		}
+		if !nearlyEqual(closest, radius, 1e-3) {
+			t.Errorf("%s: bounding sphere %v from closest side plane, want %v", c.name, closest, radius)
+		}
+	}
+}
+
+func TestOrbitPitchClamp(t *testing.T) {
+	orbit := NewOrbitController(testCameras()[0].cam)
+
+	cases := []struct {
+		name  string
+		delta float32
+		want  float32
+	}{
+		{"within limits", 0.5, 0.5},
+		{"over the top", 10, 0.49 * math.Pi},
+		{"back down", -0.1, 0.49*math.Pi - 0.1},
+		{"under the bottom", -20, -0.49 * math.Pi},
+	}
+
+	for _, c := range cases {
+		orbit.RotateBy(0, c.delta)
+		if !nearlyEqual(orbit.Pitch, c.want, testEpsilon) {
+			t.Errorf("%s: pitch %v, want %v", c.name, orbit.Pitch, c.want)
+		}
+
+		// Camera never looks straight down, so right vector stays valid
+		_, right, _ := orbit.axes()
+		if !nearlyEqual(vecLength(right), 1, testEpsilon) {
+			t.Errorf("%s: right vector %v", c.name, right)
+		}
+	}
+
+	// Inertia is clamped too
+	orbit = NewOrbitController(testCameras()[0].cam)
+	orbit.MinPitch, orbit.MaxPitch = -0.2, 0.2
+	orbit.Grab()
+	orbit.RotateBy(0, 0.1)
+	orbit.Update(0.1)
+	orbit.Release()
+	for i := 0; i < 10; i++ {
+		orbit.Update(0.1)
+	}
+	if orbit.Pitch != 0.2 {
+		t.Errorf("pitch after inertia %v, want 0.2", orbit.Pitch)
+	}
+}
+
+func TestOrbitYawLimits(t *testing.T) {
+	orbit := NewOrbitController(testCameras()[0].cam)
+	orbit.RotateBy(7, 0)
+	if !nearlyEqual(orbit.Yaw, 7-2*math.Pi, testEpsilon) {
+		t.Errorf("free yaw %v, want wrapped", orbit.Yaw)
+	}
+
+	orbit.Yaw = 0
+	orbit.MinYaw, orbit.MaxYaw = -1, 1
+	orbit.RotateBy(3, 0)
+	if orbit.Yaw != 1 {
+		t.Errorf("limited yaw %v, want 1", orbit.Yaw)
+	}
+}
@@ -0,0 +1,38 @@
+package glutils
+
+import (
+	"github.com/banthar/Go-SDL/sdl"
+)
+
+// Platform hides window, input and clock handling from AppStateManager,
+// so states can be driven either by SDL or by a headless test harness.
+// Events are passed using Go-SDL event types in both cases.
+type Platform interface {
+	// Create window with GL context
+	OpenWindow(caption string, config WindowConfig) error
+	// Change video mode of already opened window
+	SetVideoMode(config WindowConfig) error
+	WindowSize() (width, height int)
+
+	// Returns nil when there are no more pending events
+	PollEvent() sdl.Event
+	// Milliseconds since platform start
+	GetTicks() uint32
+	Delay(ms uint32)
+
+	ShowCursor(show bool)
+	WarpMouse(x, y int)
+	// Hide and lock pointer in window, so motion events report
+	// unbounded relative movement
+	SetRelativeMouse(on bool)
+	// Translate key presses to unicode and turn on key repeat
+	SetTextInput(on bool)
+
+	// Open all connected gamepads, closing ones opened before. Their
+	// events are reported by PollEvent with device index as in the list.
+	OpenGamepads() []GamepadInfo
+
+	Clear()
+	SwapBuffers()
+	Close()
+}
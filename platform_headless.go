@@ -0,0 +1,120 @@
+package glutils
+
+import (
+	"github.com/banthar/Go-SDL/sdl"
+)
+
+// Platform without window nor GL context. Events are scripted with
+// PushEvent and clock is advanced by hand, so AppStates can be
+// exercised in tests. States using GL directly won't work with it.
+type HeadlessPlatform struct {
+	Width, Height int
+
+	// Current time returned by GetTicks
+	Ticks uint32
+	// Added to Ticks after every GetTicks call, 0 means clock stands still
+	AutoAdvance uint32
+
+	Events []sdl.Event
+	// Devices reported by OpenGamepads
+	Gamepads []GamepadInfo
+
+	CursorVisible  bool
+	RelativeMouse  bool
+	TextInput      bool
+	MouseX, MouseY int
+	Config         WindowConfig
+	Clears         int
+	Swaps          int
+	Closed         bool
+}
+
+func NewHeadlessPlatform(width, height int) *HeadlessPlatform {
+	return &HeadlessPlatform{
+		Width:         width,
+		Height:        height,
+		CursorVisible: true}
+}
+
+// Queue event to be returned by PollEvent
+func (self *HeadlessPlatform) PushEvent(events ...sdl.Event) {
+	self.Events = append(self.Events, events...)
+}
+
+// Move fake clock forward by ms milliseconds
+func (self *HeadlessPlatform) Advance(ms uint32) {
+	self.Ticks += ms
+}
+
+// Window keeps size given to NewHeadlessPlatform, the rest of config
+// is stored
+func (self *HeadlessPlatform) OpenWindow(caption string, config WindowConfig) error {
+	config.Width, config.Height = self.Width, self.Height
+	self.Config = config
+	return nil
+}
+
+func (self *HeadlessPlatform) SetVideoMode(config WindowConfig) error {
+	self.Config = config
+	self.Width = config.Width
+	self.Height = config.Height
+	return nil
+}
+
+func (self *HeadlessPlatform) WindowSize() (width, height int) {
+	return self.Width, self.Height
+}
+
+func (self *HeadlessPlatform) PollEvent() sdl.Event {
+	if len(self.Events) == 0 {
+		return nil
+	}
+	event := self.Events[0]
+	self.Events = self.Events[1:]
+	return event
+}
+
+func (self *HeadlessPlatform) GetTicks() uint32 {
+	ret := self.Ticks
+	self.Ticks += self.AutoAdvance
+	return ret
+}
+
+// Sleeping just moves the fake clock
+func (self *HeadlessPlatform) Delay(ms uint32) {
+	self.Ticks += ms
+}
+
+func (self *HeadlessPlatform) ShowCursor(show bool) {
+	self.CursorVisible = show
+}
+
+func (self *HeadlessPlatform) WarpMouse(x, y int) {
+	self.MouseX = x
+	self.MouseY = y
+}
+
+func (self *HeadlessPlatform) SetRelativeMouse(on bool) {
+	self.RelativeMouse = on
+	self.CursorVisible = !on
+}
+
+func (self *HeadlessPlatform) SetTextInput(on bool) {
+	self.TextInput = on
+}
+
+func (self *HeadlessPlatform) OpenGamepads() []GamepadInfo {
+	return self.Gamepads
+}
+
+func (self *HeadlessPlatform) Clear() {
+	self.Clears++
+}
+
+func (self *HeadlessPlatform) SwapBuffers() {
+	self.Swaps++
+}
+
+func (self *HeadlessPlatform) Close() {
+	self.Closed = true
+}
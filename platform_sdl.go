@@ -0,0 +1,174 @@
+package glutils
+
+import (
+	"errors"
+	"fmt"
+	"github.com/banthar/Go-SDL/sdl"
+	"github.com/pzsz/gl"
+)
+
+// Platform implementation using SDL 1.2 window with OpenGL context
+type SDLPlatform struct {
+	Screen    *sdl.Surface
+	Joysticks []*sdl.Joystick
+}
+
+func NewSDLPlatform() *SDLPlatform {
+	return &SDLPlatform{}
+}
+
+func (self *SDLPlatform) OpenWindow(caption string, config WindowConfig) error {
+	if sdl.Init(sdl.INIT_VIDEO) != 0 {
+		return errors.New("Couldn't initialise SDL: " + sdl.GetError())
+	}
+
+	if err := self.SetVideoMode(config); err != nil {
+		sdl.Quit()
+		return err
+	}
+
+	gl.Init()
+	setupGLDebug()
+
+	sdl.WM_SetCaption(caption, caption)
+
+	Setup()
+	return nil
+}
+
+func (self *SDLPlatform) SetVideoMode(config WindowConfig) error {
+	sdl.GL_SetAttribute(sdl.GL_DOUBLEBUFFER, 1)
+	sdl.GL_SetAttribute(sdl.GL_DEPTH_SIZE, config.DepthBits)
+	sdl.GL_SetAttribute(sdl.GL_STENCIL_SIZE, config.StencilBits)
+
+	if config.Samples > 0 {
+		sdl.GL_SetAttribute(sdl.GL_MULTISAMPLEBUFFERS, 1)
+		sdl.GL_SetAttribute(sdl.GL_MULTISAMPLESAMPLES, config.Samples)
+	} else {
+		sdl.GL_SetAttribute(sdl.GL_MULTISAMPLEBUFFERS, 0)
+		sdl.GL_SetAttribute(sdl.GL_MULTISAMPLESAMPLES, 0)
+	}
+
+	if config.VSync {
+		sdl.GL_SetAttribute(sdl.GL_SWAP_CONTROL, 1)
+	} else {
+		sdl.GL_SetAttribute(sdl.GL_SWAP_CONTROL, 0)
+	}
+
+	var flags uint32 = sdl.OPENGL
+	if config.Fullscreen {
+		flags |= sdl.FULLSCREEN
+	}
+	if config.Resizable {
+		flags |= sdl.RESIZABLE
+	}
+
+	screen := sdl.SetVideoMode(config.Width, config.Height, config.BitsPerPixel, flags)
+	if screen == nil {
+		return fmt.Errorf("Couldn't set %dx%d GL video mode: %s",
+			config.Width, config.Height, sdl.GetError())
+	}
+	self.Screen = screen
+
+	gl.Viewport(0, 0, int(screen.W), int(screen.H))
+	return nil
+}
+
+func (self *SDLPlatform) WindowSize() (width, height int) {
+	if self.Screen == nil {
+		return 0, 0
+	}
+	return int(self.Screen.W), int(self.Screen.H)
+}
+
+func (self *SDLPlatform) PollEvent() sdl.Event {
+	return sdl.PollEvent()
+}
+
+func (self *SDLPlatform) GetTicks() uint32 {
+	return sdl.GetTicks()
+}
+
+func (self *SDLPlatform) Delay(ms uint32) {
+	sdl.Delay(ms)
+}
+
+func (self *SDLPlatform) ShowCursor(show bool) {
+	if show {
+		sdl.ShowCursor(1)
+	} else {
+		sdl.ShowCursor(0)
+	}
+}
+
+func (self *SDLPlatform) WarpMouse(x, y int) {
+	// Don't let warp generate motion event of its own
+	sdl.EventState(sdl.MOUSEMOTION, sdl.IGNORE)
+	sdl.WarpMouse(x, y)
+	sdl.EventState(sdl.MOUSEMOTION, sdl.ENABLE)
+}
+
+func (self *SDLPlatform) SetRelativeMouse(on bool) {
+	// SDL 1.2 gives relative motion with hidden cursor and grabbed input
+	self.ShowCursor(!on)
+	if on {
+		sdl.WM_GrabInput(sdl.GRAB_ON)
+	} else {
+		sdl.WM_GrabInput(sdl.GRAB_OFF)
+	}
+}
+
+func (self *SDLPlatform) SetTextInput(on bool) {
+	if on {
+		sdl.EnableUNICODE(1)
+		sdl.EnableKeyRepeat(sdl.DEFAULT_REPEAT_DELAY, sdl.DEFAULT_REPEAT_INTERVAL)
+	} else {
+		sdl.EnableUNICODE(0)
+		sdl.EnableKeyRepeat(0, 0)
+	}
+}
+
+func (self *SDLPlatform) OpenGamepads() []GamepadInfo {
+	for _, joy := range self.Joysticks {
+		joy.Close()
+	}
+	self.Joysticks = nil
+
+	// Restarting subsystem is the only way to see new devices in SDL 1.2
+	if sdl.WasInit(sdl.INIT_JOYSTICK) != 0 {
+		sdl.QuitSubSystem(sdl.INIT_JOYSTICK)
+	}
+	if sdl.InitSubSystem(sdl.INIT_JOYSTICK) != 0 {
+		return nil
+	}
+	sdl.JoystickEventState(sdl.ENABLE)
+
+	ret := []GamepadInfo{}
+	for i := 0; i < sdl.NumJoysticks(); i++ {
+		joy := sdl.JoystickOpen(i)
+		if joy == nil {
+			break
+		}
+		self.Joysticks = append(self.Joysticks, joy)
+		ret = append(ret, GamepadInfo{sdl.JoystickName(i),
+			joy.NumAxes(), joy.NumButtons(), joy.NumHats()})
+	}
+	return ret
+}
+
+func (self *SDLPlatform) Clear() {
+	Clear()
+}
+
+func (self *SDLPlatform) SwapBuffers() {
+	defer checkGL("SwapBuffers")
+	sdl.GL_SwapBuffers()
+}
+
+func (self *SDLPlatform) Close() {
+	for _, joy := range self.Joysticks {
+		joy.Close()
+	}
+	self.Joysticks = nil
+	sdl.Quit()
+}
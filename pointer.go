@@ -0,0 +1,232 @@
+package glutils
+
+import (
+	"github.com/banthar/Go-SDL/sdl"
+)
+
+// SDL 1.2 reports wheel as presses of these buttons
+const (
+	MOUSE_WHEEL_UP   = 4
+	MOUSE_WHEEL_DOWN = 5
+)
+
+// Optional interface for states that want wheel movement. Delta is
+// positive for wheel moved up.
+type WheelHandler interface {
+	OnMouseWheel(x, y float32, delta int)
+}
+
+// Optional interface for states that want drag gestures. Drag starts
+// when pointer moves further than DragThreshold with button held.
+type DragHandler interface {
+	OnDragStart(button int, originX, originY float32)
+	OnDragMove(button int, originX, originY, x, y float32)
+	OnDragEnd(button int, originX, originY, x, y float32)
+}
+
+// Optional interface for states that want double clicks. It's called
+// on second press, after the usual OnMouseClick.
+type DoubleClickHandler interface {
+	OnDoubleClick(x, y float32, button int)
+}
+
+// Rectangle of screen that reports pointer entering and leaving it
+type HoverRegion struct {
+	Id         string
+	X, Y, W, H float32
+}
+
+func (self *HoverRegion) Contains(x, y float32) bool {
+	return x >= self.X && x < self.X+self.W &&
+		y >= self.Y && y < self.Y+self.H
+}
+
+// Optional interface for states with hover regions. HoverRegions is
+// asked on every pointer move, so regions may change at any time.
+type HoverHandler interface {
+	HoverRegions() []HoverRegion
+	OnHoverEnter(region HoverRegion)
+	OnHoverLeave(region HoverRegion)
+}
+
+type pointerButton struct {
+	down             bool
+	dragging         bool
+	originX, originY float32
+}
+
+// Turns raw mouse events into wheel, drag, double click and hover
+// events for AppStateManager
+type PointerTracker struct {
+	// Distance in pixels pointer has to move to start drag
+	DragThreshold float32
+	// Max time in seconds and distance in pixels between clicks
+	// to count as double click
+	DoubleClickTime     float32
+	DoubleClickDistance float32
+
+	buttons map[int]*pointerButton
+
+	lastClickButton        int
+	lastClickTime          float32
+	lastClickX, lastClickY float32
+
+	hovered map[AppState][]HoverRegion
+}
+
+func NewPointerTracker() *PointerTracker {
+	return &PointerTracker{
+		DragThreshold:       4,
+		DoubleClickTime:     0.4,
+		DoubleClickDistance: 4,
+		buttons:             map[int]*pointerButton{},
+		hovered:             map[AppState][]HoverRegion{}}
+}
+
+func (self *PointerTracker) getButton(button int) *pointerButton {
+	ret := self.buttons[button]
+	if ret == nil {
+		ret = &pointerButton{}
+		self.buttons[button] = ret
+	}
+	return ret
+}
+
+func pointerDistance(x1, y1, x2, y2 float32) float32 {
+	dx, dy := x2-x1, y2-y1
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
+
+// Handle button event, now is manager time in seconds
+func (self *PointerTracker) ButtonEvent(man *AppStateManager, event sdl.Event, x, y float32, button int, down bool, now float32) {
+	if button == MOUSE_WHEEL_UP || button == MOUSE_WHEEL_DOWN {
+		if down {
+			delta := 1
+			if button == MOUSE_WHEEL_DOWN {
+				delta = -1
+			}
+			man.dispatchInput(event, func(state AppState) {
+				if handler, ok := state.(WheelHandler); ok {
+					handler.OnMouseWheel(x, y, delta)
+				}
+			})
+		}
+		return
+	}
+
+	b := self.getButton(button)
+	if down {
+		b.down = true
+		b.dragging = false
+		b.originX, b.originY = x, y
+
+		if button == self.lastClickButton &&
+			now-self.lastClickTime <= self.DoubleClickTime &&
+			pointerDistance(x, y, self.lastClickX, self.lastClickY) <= self.DoubleClickDistance {
+			// Third click doesn't make another double click
+			self.lastClickButton = 0
+			man.dispatchInput(event, func(state AppState) {
+				if handler, ok := state.(DoubleClickHandler); ok {
+					handler.OnDoubleClick(x, y, button)
+				}
+			})
+		} else {
+			self.lastClickButton = button
+			self.lastClickTime = now
+			self.lastClickX, self.lastClickY = x, y
+		}
+		return
+	}
+
+	if b.dragging {
+		man.dispatchInput(event, func(state AppState) {
+			if handler, ok := state.(DragHandler); ok {
+				handler.OnDragEnd(button, b.originX, b.originY, x, y)
+			}
+		})
+	}
+	b.down = false
+	b.dragging = false
+}
+
+func (self *PointerTracker) MotionEvent(man *AppStateManager, event sdl.Event, x, y float32) {
+	for button, b := range self.buttons {
+		if !b.down {
+			continue
+		}
+
+		if !b.dragging {
+			if pointerDistance(x, y, b.originX, b.originY) < self.DragThreshold {
+				continue
+			}
+			b.dragging = true
+			man.dispatchInput(event, func(state AppState) {
+				if handler, ok := state.(DragHandler); ok {
+					handler.OnDragStart(button, b.originX, b.originY)
+				}
+			})
+		}
+
+		man.dispatchInput(event, func(state AppState) {
+			if handler, ok := state.(DragHandler); ok {
+				handler.OnDragMove(button, b.originX, b.originY, x, y)
+			}
+		})
+	}
+
+	man.dispatchInput(event, func(state AppState) {
+		if handler, ok := state.(HoverHandler); ok {
+			self.updateHover(state, handler, x, y)
+		}
+	})
+}
+
+func (self *PointerTracker) updateHover(state AppState, handler HoverHandler, x, y float32) {
+	previous := self.hovered[state]
+	current := []HoverRegion{}
+	for _, region := range handler.HoverRegions() {
+		if region.Contains(x, y) {
+			current = append(current, region)
+		}
+	}
+
+	for _, region := range previous {
+		if !containsRegion(current, region.Id) {
+			handler.OnHoverLeave(region)
+		}
+	}
+	for _, region := range current {
+		if !containsRegion(previous, region.Id) {
+			handler.OnHoverEnter(region)
+		}
+	}
+
+	if len(current) == 0 {
+		delete(self.hovered, state)
+	} else {
+		self.hovered[state] = current
+	}
+}
+
+func containsRegion(regions []HoverRegion, id string) bool {
+	for _, region := range regions {
+		if region.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Drop hover tracking of state that is gone
+func (self *PointerTracker) forgetState(state AppState) {
+	delete(self.hovered, state)
+}
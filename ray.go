@@ -0,0 +1,118 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+)
+
+// Half line starting at Origin, Direction is normalised
+type Ray struct {
+	Origin    v.Vector3f
+	Direction v.Vector3f
+}
+
+func NewRay(origin, direction v.Vector3f) Ray {
+	return Ray{origin, vecNormalize(direction)}
+}
+
+// Point at distance t from origin
+func (self Ray) At(t float32) v.Vector3f {
+	return vecAdd(self.Origin, vecScale(self.Direction, t))
+}
+
+func vecAdd(a, b v.Vector3f) v.Vector3f {
+	return v.Vector3f{a.X + b.X, a.Y + b.Y, a.Z + b.Z}
+}
+
+func vecSub(a, b v.Vector3f) v.Vector3f {
+	return v.Vector3f{a.X - b.X, a.Y - b.Y, a.Z - b.Z}
+}
+
+func vecScale(a v.Vector3f, s float32) v.Vector3f {
+	return v.Vector3f{a.X * s, a.Y * s, a.Z * s}
+}
+
+func vecDot(a, b v.Vector3f) float32 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func vecCross(a, b v.Vector3f) v.Vector3f {
+	return v.Vector3f{
+		a.Y*b.Z - a.Z*b.Y,
+		a.Z*b.X - a.X*b.Z,
+		a.X*b.Y - a.Y*b.X}
+}
+
+func vecLength(a v.Vector3f) float32 {
+	return float32(math.Sqrt(float64(vecDot(a, a))))
+}
+
+// Zero vector stays zero
+func vecNormalize(a v.Vector3f) v.Vector3f {
+	l := vecLength(a)
+	if l == 0 {
+		return a
+	}
+	return vecScale(a, 1/l)
+}
+
+// Product a*b of column major matrices, as OpenGL composes them
+func matrixMul(a, b *v.Matrix4) v.Matrix4 {
+	var ret v.Matrix4
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			var sum float32
+			for i := 0; i < 4; i++ {
+				sum += a[i*4+row] * b[col*4+i]
+			}
+			ret[col*4+row] = sum
+		}
+	}
+	return ret
+}
+
+// Transform x,y,z,w by column major matrix
+func matrixTransform(m *v.Matrix4, x, y, z, w float32) (rx, ry, rz, rw float32) {
+	rx = m[0]*x + m[4]*y + m[8]*z + m[12]*w
+	ry = m[1]*x + m[5]*y + m[9]*z + m[13]*w
+	rz = m[2]*x + m[6]*y + m[10]*z + m[14]*w
+	rw = m[3]*x + m[7]*y + m[11]*z + m[15]*w
+	return
+}
+
+// Inverse by cofactors, computed in float64. Returns false for
+// singular matrix.
+func matrixInvert(m *v.Matrix4) (v.Matrix4, bool) {
+	var a, inv [16]float64
+	for i := range m {
+		a[i] = float64(m[i])
+	}
+
+	inv[0] = a[5]*a[10]*a[15] - a[5]*a[11]*a[14] - a[9]*a[6]*a[15] + a[9]*a[7]*a[14] + a[13]*a[6]*a[11] - a[13]*a[7]*a[10]
+	inv[4] = -a[4]*a[10]*a[15] + a[4]*a[11]*a[14] + a[8]*a[6]*a[15] - a[8]*a[7]*a[14] - a[12]*a[6]*a[11] + a[12]*a[7]*a[10]
+	inv[8] = a[4]*a[9]*a[15] - a[4]*a[11]*a[13] - a[8]*a[5]*a[15] + a[8]*a[7]*a[13] + a[12]*a[5]*a[11] - a[12]*a[7]*a[9]
+	inv[12] = -a[4]*a[9]*a[14] + a[4]*a[10]*a[13] + a[8]*a[5]*a[14] - a[8]*a[6]*a[13] - a[12]*a[5]*a[10] + a[12]*a[6]*a[9]
+	inv[1] = -a[1]*a[10]*a[15] + a[1]*a[11]*a[14] + a[9]*a[2]*a[15] - a[9]*a[3]*a[14] - a[13]*a[2]*a[11] + a[13]*a[3]*a[10]
+	inv[5] = a[0]*a[10]*a[15] - a[0]*a[11]*a[14] - a[8]*a[2]*a[15] + a[8]*a[3]*a[14] + a[12]*a[2]*a[11] - a[12]*a[3]*a[10]
+	inv[9] = -a[0]*a[9]*a[15] + a[0]*a[11]*a[13] + a[8]*a[1]*a[15] - a[8]*a[3]*a[13] - a[12]*a[1]*a[11] + a[12]*a[3]*a[9]
+	inv[13] = a[0]*a[9]*a[14] - a[0]*a[10]*a[13] - a[8]*a[1]*a[14] + a[8]*a[2]*a[13] + a[12]*a[1]*a[10] - a[12]*a[2]*a[9]
+	inv[2] = a[1]*a[6]*a[15] - a[1]*a[7]*a[14] - a[5]*a[2]*a[15] + a[5]*a[3]*a[14] + a[13]*a[2]*a[7] - a[13]*a[3]*a[6]
+	inv[6] = -a[0]*a[6]*a[15] + a[0]*a[7]*a[14] + a[4]*a[2]*a[15] - a[4]*a[3]*a[14] - a[12]*a[2]*a[7] + a[12]*a[3]*a[6]
+	inv[10] = a[0]*a[5]*a[15] - a[0]*a[7]*a[13] - a[4]*a[1]*a[15] + a[4]*a[3]*a[13] + a[12]*a[1]*a[7] - a[12]*a[3]*a[5]
+	inv[14] = -a[0]*a[5]*a[14] + a[0]*a[6]*a[13] + a[4]*a[1]*a[14] - a[4]*a[2]*a[13] - a[12]*a[1]*a[6] + a[12]*a[2]*a[5]
+	inv[3] = -a[1]*a[6]*a[11] + a[1]*a[7]*a[10] + a[5]*a[2]*a[11] - a[5]*a[3]*a[10] - a[9]*a[2]*a[7] + a[9]*a[3]*a[6]
+	inv[7] = a[0]*a[6]*a[11] - a[0]*a[7]*a[10] - a[4]*a[2]*a[11] + a[4]*a[3]*a[10] + a[8]*a[2]*a[7] - a[8]*a[3]*a[6]
+	inv[11] = -a[0]*a[5]*a[11] + a[0]*a[7]*a[9] + a[4]*a[1]*a[11] - a[4]*a[3]*a[9] - a[8]*a[1]*a[7] + a[8]*a[3]*a[5]
+	inv[15] = a[0]*a[5]*a[10] - a[0]*a[6]*a[9] - a[4]*a[1]*a[10] + a[4]*a[2]*a[9] + a[8]*a[1]*a[6] - a[8]*a[2]*a[5]
+
+	det := a[0]*inv[0] + a[1]*inv[4] + a[2]*inv[8] + a[3]*inv[12]
+	if det == 0 {
+		return v.Matrix4{}, false
+	}
+
+	var ret v.Matrix4
+	for i := range inv {
+		ret[i] = float32(inv[i] / det)
+	}
+	return ret, true
+}
@@ -0,0 +1,142 @@
+package glutils
+
+import (
+	v "github.com/pzsz/lin3dmath"
+	"math"
+	"testing"
+)
+
+const testEpsilon = 1e-4
+
+func nearlyEqual(a, b, epsilon float32) bool {
+	return math.Abs(float64(a-b)) <= float64(epsilon)*math.Max(1, math.Abs(float64(b)))
+}
+
+func vecNearlyEqual(a, b v.Vector3f, epsilon float32) bool {
+	return nearlyEqual(a.X, b.X, epsilon) && nearlyEqual(a.Y, b.Y, epsilon) && nearlyEqual(a.Z, b.Z, epsilon)
+}
+
+func matrixNearlyEqual(a, b *v.Matrix4, epsilon float32) bool {
+	for i := range a {
+		if !nearlyEqual(a[i], b[i], epsilon) {
+			return false
+		}
+	}
+	return true
+}
+
+var identityMatrix = v.Matrix4{
+	1, 0, 0, 0,
+	0, 1, 0, 0,
+	0, 0, 1, 0,
+	0, 0, 0, 1}
+
+func translationMatrix(x, y, z float32) v.Matrix4 {
+	m := identityMatrix
+	m[12], m[13], m[14] = x, y, z
+	return m
+}
+
+// Column major view matrix of camera at eye looking at centre, same as
+// gluLookAt gives
+func lookAtMatrix(eye, centre, up v.Vector3f) v.Matrix4 {
+	f := vecNormalize(vecSub(centre, eye))
+	s := vecNormalize(vecCross(f, up))
+	u := vecCross(s, f)
+	return v.Matrix4{
+		s.X, u.X, -f.X, 0,
+		s.Y, u.Y, -f.Y, 0,
+		s.Z, u.Z, -f.Z, 0,
+		-vecDot(s, eye), -vecDot(u, eye), vecDot(f, eye), 1}
+}
+
+func TestMatrixMul(t *testing.T) {
+	scale := v.Matrix4{
+		2, 0, 0, 0,
+		0, 3, 0, 0,
+		0, 0, 4, 0,
+		0, 0, 0, 1}
+	translate := translationMatrix(1, 2, 3)
+
+	cases := []struct {
+		name  string
+		a, b  v.Matrix4
+		point v.Vector3f
+		want  v.Vector3f
+	}{
+		{"identity", identityMatrix, identityMatrix, v.Vector3f{1, 2, 3}, v.Vector3f{1, 2, 3}},
+		{"translate after scale", translate, scale, v.Vector3f{1, 1, 1}, v.Vector3f{3, 5, 7}},
+		{"scale after translate", scale, translate, v.Vector3f{1, 1, 1}, v.Vector3f{4, 9, 16}},
+	}
+
+	for _, c := range cases {
+		m := matrixMul(&c.a, &c.b)
+		x, y, z, w := matrixTransform(&m, c.point.X, c.point.Y, c.point.Z, 1)
+		if got := (v.Vector3f{x, y, z}); w != 1 || !vecNearlyEqual(got, c.want, testEpsilon) {
+			t.Errorf("%s: got %v w=%v, want %v", c.name, got, w, c.want)
+		}
+	}
+}
+
+func TestMatrixInvert(t *testing.T) {
+	lookAt := lookAtMatrix(v.Vector3f{5, 3, 8}, v.Vector3f{0, 0, 0}, v.Vector3f{0, 1, 0})
+	frustum := *CreateFrustrumMatrix(-1, 1, -0.75, 0.75, 1, 100)
+
+	cases := []struct {
+		name string
+		m    v.Matrix4
+	}{
+		{"identity", identityMatrix},
+		{"translation", translationMatrix(1, -2, 3)},
+		{"look at", lookAt},
+		{"frustum", frustum},
+		{"ortho", *CreateOrthoMatrix(0, 800, 0, 600, -1, 1)},
+		{"frustum times look at", matrixMul(&frustum, &lookAt)},
+	}
+
+	for _, c := range cases {
+		inverse, ok := matrixInvert(&c.m)
+		if !ok {
+			t.Errorf("%s: reported singular", c.name)
+			continue
+		}
+		if product := matrixMul(&c.m, &inverse); !matrixNearlyEqual(&product, &identityMatrix, testEpsilon) {
+			t.Errorf("%s: m*inverse = %v", c.name, product)
+		}
+	}
+}
+
+func TestMatrixInvertSingular(t *testing.T) {
+	cases := []struct {
+		name string
+		m    v.Matrix4
+	}{
+		{"zero", v.Matrix4{}},
+		{"zero column", v.Matrix4{
+			1, 0, 0, 0,
+			0, 0, 0, 0,
+			0, 0, 1, 0,
+			0, 0, 0, 1}},
+		{"equal columns", v.Matrix4{
+			1, 2, 3, 4,
+			1, 2, 3, 4,
+			0, 0, 1, 0,
+			0, 0, 0, 1}},
+	}
+
+	for _, c := range cases {
+		if inverse, ok := matrixInvert(&c.m); ok {
+			t.Errorf("%s: inverted to %v", c.name, inverse)
+		}
+	}
+}
+
+func TestRay(t *testing.T) {
+	ray := NewRay(v.Vector3f{1, 0, 0}, v.Vector3f{0, 0, -5})
+	if ray.Direction != (v.Vector3f{0, 0, -1}) {
+		t.Errorf("direction not normalised: %v", ray.Direction)
+	}
+	if got := ray.At(2); got != (v.Vector3f{1, 0, -2}) {
+		t.Errorf("At(2) = %v", got)
+	}
+}
@@ -0,0 +1,398 @@
+package glutils
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"github.com/banthar/Go-SDL/sdl"
+	"io"
+	"math"
+	"os"
+)
+
+const recordingMagic = "GLUREC1\n"
+
+// Record kinds in recording file
+const (
+	recFrame = iota + 1
+	recQuit
+	recResize
+	recKey
+	recMouseMove
+	recMouseButton
+	recJoyAxis
+	recJoyButton
+	recJoyHat
+	recGamepads
+)
+
+type recFrameData struct {
+	Frame    uint32
+	TimeStep float32
+}
+
+type recResizeData struct {
+	W, H int32
+}
+
+type recKeyData struct {
+	State    uint8
+	Scancode uint8
+	Sym      uint32
+	Mod      uint32
+	Unicode  uint16
+}
+
+type recMouseMoveData struct {
+	X, Y   uint16
+	DX, DY float32
+}
+
+type recMouseButtonData struct {
+	Button, State uint8
+	X, Y          uint16
+}
+
+type recJoyAxisData struct {
+	Which, Axis uint8
+	Value       int16
+}
+
+type recJoyButtonData struct {
+	Which, Button, State uint8
+}
+
+type recJoyHatData struct {
+	Which, Hat, Value uint8
+}
+
+// Followed by NameLength bytes of name
+type recGamepadData struct {
+	Axes, Buttons, Hats uint8
+	NameLength          uint16
+}
+
+// Whether event is of kind InputRecorder stores
+func recordedEvent(event sdl.Event) bool {
+	switch event.(type) {
+	case *sdl.QuitEvent, *sdl.ResizeEvent, *sdl.KeyboardEvent,
+		*sdl.MouseMotionEvent, *sdl.MouseButtonEvent, *sdl.JoyAxisEvent,
+		*sdl.JoyButtonEvent, *sdl.JoyHatEvent, *gamepadsEvent:
+		return true
+	}
+	return false
+}
+
+// Replayed in place of device rescan, so recorded gamepad events reach
+// the same devices
+type gamepadsEvent struct {
+	Gamepads []GamepadInfo
+}
+
+// Writes events dispatched by AppStateManager, frame by frame, to
+// a compact binary file that can be fed back with InputReplayer
+type InputRecorder struct {
+	writer *bufio.Writer
+	closer io.Closer
+	err    error
+
+	// Devices waiting for the first frame
+	started  bool
+	gamepads *gamepadsEvent
+}
+
+func NewInputRecorder(w io.Writer) *InputRecorder {
+	ret := &InputRecorder{writer: bufio.NewWriter(w)}
+	ret.write([]byte(recordingMagic))
+	return ret
+}
+
+func CreateInputRecording(filename string) (*InputRecorder, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := NewInputRecorder(f)
+	ret.closer = f
+	return ret, nil
+}
+
+func (self *InputRecorder) write(data interface{}) {
+	if self.err == nil {
+		self.err = binary.Write(self.writer, byteOrder, data)
+	}
+}
+
+func (self *InputRecorder) writeRecord(kind uint8, data interface{}) {
+	self.write(kind)
+	if data != nil {
+		self.write(data)
+	}
+}
+
+func (self *InputRecorder) BeginFrame(frame uint32, time_step float32) {
+	self.writeRecord(recFrame, &recFrameData{frame, time_step})
+	self.started = true
+	if self.gamepads != nil {
+		self.RecordEvent(self.gamepads, 0, 0)
+		self.gamepads = nil
+	}
+}
+
+// Store list of opened gamepads. Before the first frame it's kept
+// until BeginFrame.
+func (self *InputRecorder) RecordGamepads(gamepads []GamepadInfo) {
+	event := &gamepadsEvent{gamepads}
+	if self.started {
+		self.RecordEvent(event, 0, 0)
+	} else {
+		self.gamepads = event
+	}
+}
+
+// Store event, dx and dy are used for mouse motion only
+func (self *InputRecorder) RecordEvent(event sdl.Event, dx, dy float32) {
+	switch event.(type) {
+	case *sdl.QuitEvent:
+		self.writeRecord(recQuit, nil)
+	case *sdl.ResizeEvent:
+		re := event.(*sdl.ResizeEvent)
+		self.writeRecord(recResize, &recResizeData{re.W, re.H})
+	case *sdl.KeyboardEvent:
+		ke := event.(*sdl.KeyboardEvent)
+		self.writeRecord(recKey, &recKeyData{ke.State, ke.Keysym.Scancode,
+			ke.Keysym.Sym, ke.Keysym.Mod, ke.Keysym.Unicode})
+	case *sdl.MouseMotionEvent:
+		me := event.(*sdl.MouseMotionEvent)
+		self.writeRecord(recMouseMove, &recMouseMoveData{me.X, me.Y, dx, dy})
+	case *sdl.MouseButtonEvent:
+		me := event.(*sdl.MouseButtonEvent)
+		self.writeRecord(recMouseButton, &recMouseButtonData{me.Button, me.State, me.X, me.Y})
+	case *sdl.JoyAxisEvent:
+		je := event.(*sdl.JoyAxisEvent)
+		self.writeRecord(recJoyAxis, &recJoyAxisData{je.Which, je.Axis, je.Value})
+	case *sdl.JoyButtonEvent:
+		je := event.(*sdl.JoyButtonEvent)
+		self.writeRecord(recJoyButton, &recJoyButtonData{je.Which, je.Button, je.State})
+	case *sdl.JoyHatEvent:
+		je := event.(*sdl.JoyHatEvent)
+		self.writeRecord(recJoyHat, &recJoyHatData{je.Which, je.Hat, je.Value})
+	case *gamepadsEvent:
+		ge := event.(*gamepadsEvent)
+		self.writeRecord(recGamepads, uint8(len(ge.Gamepads)))
+		for _, info := range ge.Gamepads {
+			self.write(&recGamepadData{uint8(info.Axes), uint8(info.Buttons),
+				uint8(info.Hats), uint16(len(info.Name))})
+			self.write([]byte(info.Name))
+		}
+	}
+}
+
+// First error that occurred while writing, if any
+func (self *InputRecorder) Err() error {
+	return self.err
+}
+
+func (self *InputRecorder) Close() error {
+	if self.err == nil {
+		self.err = self.writer.Flush()
+	}
+	if self.closer != nil {
+		if err := self.closer.Close(); self.err == nil {
+			self.err = err
+		}
+	}
+	return self.err
+}
+
+type replayFrame struct {
+	frame     uint32
+	time_step float32
+	events    []sdl.Event
+}
+
+// Plays back file written by InputRecorder
+type InputReplayer struct {
+	frames  []replayFrame
+	current int
+	events  []sdl.Event
+	// Recorded motion, Xrel and Yrel of replayed events are rounded
+	deltas map[*sdl.MouseMotionEvent][2]float32
+}
+
+func NewInputReplayer(r io.Reader) (*InputReplayer, error) {
+	reader := bufio.NewReader(r)
+
+	magic := make([]byte, len(recordingMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != recordingMagic {
+		return nil, errors.New("Not an input recording")
+	}
+
+	ret := &InputReplayer{current: -1,
+		deltas: make(map[*sdl.MouseMotionEvent][2]float32)}
+	for {
+		kind, err := reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if kind == recFrame {
+			data := recFrameData{}
+			if err := binary.Read(reader, byteOrder, &data); err != nil {
+				return nil, err
+			}
+			ret.frames = append(ret.frames, replayFrame{data.Frame, data.TimeStep, nil})
+			continue
+		}
+
+		if len(ret.frames) == 0 {
+			return nil, errors.New("Input recording event before first frame")
+		}
+
+		event, err := ret.readEvent(reader, kind)
+		if err != nil {
+			return nil, err
+		}
+		last := &ret.frames[len(ret.frames)-1]
+		last.events = append(last.events, event)
+	}
+	return ret, nil
+}
+
+func OpenInputReplay(filename string) (*InputReplayer, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return NewInputReplayer(f)
+}
+
+func (self *InputReplayer) readEvent(r io.Reader, kind byte) (sdl.Event, error) {
+	switch kind {
+	case recQuit:
+		return &sdl.QuitEvent{Type: sdl.QUIT}, nil
+	case recResize:
+		data := recResizeData{}
+		err := binary.Read(r, byteOrder, &data)
+		return &sdl.ResizeEvent{Type: sdl.VIDEORESIZE, W: data.W, H: data.H}, err
+	case recKey:
+		data := recKeyData{}
+		err := binary.Read(r, byteOrder, &data)
+		eventType := uint8(sdl.KEYUP)
+		if data.State == 1 {
+			eventType = sdl.KEYDOWN
+		}
+		event := &sdl.KeyboardEvent{Type: eventType, State: data.State}
+		event.Keysym.Scancode = data.Scancode
+		event.Keysym.Sym = data.Sym
+		event.Keysym.Mod = data.Mod
+		event.Keysym.Unicode = data.Unicode
+		return event, err
+	case recMouseMove:
+		data := recMouseMoveData{}
+		err := binary.Read(r, byteOrder, &data)
+		event := &sdl.MouseMotionEvent{Type: sdl.MOUSEMOTION, X: data.X, Y: data.Y,
+			Xrel: int16(math.Floor(float64(data.DX) + 0.5)),
+			Yrel: int16(math.Floor(float64(data.DY) + 0.5))}
+		self.deltas[event] = [2]float32{data.DX, data.DY}
+		return event, err
+	case recMouseButton:
+		data := recMouseButtonData{}
+		err := binary.Read(r, byteOrder, &data)
+		eventType := uint8(sdl.MOUSEBUTTONUP)
+		if data.State == 1 {
+			eventType = sdl.MOUSEBUTTONDOWN
+		}
+		return &sdl.MouseButtonEvent{Type: eventType, Button: data.Button,
+			State: data.State, X: data.X, Y: data.Y}, err
+	case recJoyAxis:
+		data := recJoyAxisData{}
+		err := binary.Read(r, byteOrder, &data)
+		return &sdl.JoyAxisEvent{Type: sdl.JOYAXISMOTION, Which: data.Which,
+			Axis: data.Axis, Value: data.Value}, err
+	case recJoyButton:
+		data := recJoyButtonData{}
+		err := binary.Read(r, byteOrder, &data)
+		eventType := uint8(sdl.JOYBUTTONUP)
+		if data.State == 1 {
+			eventType = sdl.JOYBUTTONDOWN
+		}
+		return &sdl.JoyButtonEvent{Type: eventType, Which: data.Which,
+			Button: data.Button, State: data.State}, err
+	case recJoyHat:
+		data := recJoyHatData{}
+		err := binary.Read(r, byteOrder, &data)
+		return &sdl.JoyHatEvent{Type: sdl.JOYHATMOTION, Which: data.Which,
+			Hat: data.Hat, Value: data.Value}, err
+	case recGamepads:
+		return readRecordedGamepads(r)
+	}
+	return nil, errors.New("Unknown record in input recording")
+}
+
+func readRecordedGamepads(r io.Reader) (sdl.Event, error) {
+	var count uint8
+	if err := binary.Read(r, byteOrder, &count); err != nil {
+		return nil, err
+	}
+
+	event := &gamepadsEvent{}
+	for i := 0; i < int(count); i++ {
+		data := recGamepadData{}
+		if err := binary.Read(r, byteOrder, &data); err != nil {
+			return nil, err
+		}
+		name := make([]byte, data.NameLength)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, err
+		}
+		event.Gamepads = append(event.Gamepads, GamepadInfo{string(name),
+			int(data.Axes), int(data.Buttons), int(data.Hats)})
+	}
+	return event, nil
+}
+
+// Move to next recorded frame, returns its time step. ok is false when
+// recording is over.
+func (self *InputReplayer) NextFrame() (time_step float32, ok bool) {
+	self.current++
+	if self.current >= len(self.frames) {
+		self.events = nil
+		return 0, false
+	}
+
+	frame := &self.frames[self.current]
+	self.events = frame.events
+	return frame.time_step, true
+}
+
+// Events of current frame, nil when there are no more
+func (self *InputReplayer) PollEvent() sdl.Event {
+	if len(self.events) == 0 {
+		return nil
+	}
+	event := self.events[0]
+	self.events = self.events[1:]
+	return event
+}
+
+// Motion as it was dispatched when recording, ok is false for events
+// that don't come from recording
+func (self *InputReplayer) motionDelta(event *sdl.MouseMotionEvent) (dx, dy float32, ok bool) {
+	delta, ok := self.deltas[event]
+	return delta[0], delta[1], ok
+}
+
+func (self *InputReplayer) Finished() bool {
+	return self.current >= len(self.frames)
+}
+
+func (self *InputReplayer) FrameCount() int {
+	return len(self.frames)
+}
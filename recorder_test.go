@@ -0,0 +1,132 @@
+package glutils
+
+import (
+	"bytes"
+	"github.com/banthar/Go-SDL/sdl"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRecorderRoundTrip(t *testing.T) {
+	key := keyEvent(sdl.K_a, true)
+	key.Keysym.Mod, key.Keysym.Unicode = sdl.KMOD_LSHIFT, 'A'
+
+	cases := []struct {
+		name   string
+		event  sdl.Event
+		dx, dy float32
+	}{
+		{"quit", &sdl.QuitEvent{Type: sdl.QUIT}, 0, 0},
+		{"resize", &sdl.ResizeEvent{Type: sdl.VIDEORESIZE, W: 640, H: 480}, 0, 0},
+		{"key down", key, 0, 0},
+		{"key up", keyEvent(sdl.K_a, false), 0, 0},
+		{"mouse move", &sdl.MouseMotionEvent{Type: sdl.MOUSEMOTION, X: 10, Y: 20, Xrel: 4, Yrel: -1}, 3.5, -1.25},
+		{"mouse down", &sdl.MouseButtonEvent{Type: sdl.MOUSEBUTTONDOWN, Button: 1, State: 1, X: 5, Y: 6}, 0, 0},
+		{"mouse up", &sdl.MouseButtonEvent{Type: sdl.MOUSEBUTTONUP, Button: 3, X: 5, Y: 6}, 0, 0},
+		{"joy axis", &sdl.JoyAxisEvent{Type: sdl.JOYAXISMOTION, Which: 1, Axis: 2, Value: -300}, 0, 0},
+		{"joy button", &sdl.JoyButtonEvent{Type: sdl.JOYBUTTONDOWN, Which: 1, Button: 4, State: 1}, 0, 0},
+		{"joy hat", &sdl.JoyHatEvent{Type: sdl.JOYHATMOTION, Hat: 1, Value: sdl.HAT_UP}, 0, 0},
+		{"gamepads", &gamepadsEvent{[]GamepadInfo{{"pad", 6, 12, 1}}}, 0, 0},
+	}
+
+	buffer := &bytes.Buffer{}
+	recorder := NewInputRecorder(buffer)
+	recorder.BeginFrame(0, 0.016)
+	for _, c := range cases {
+		recorder.RecordEvent(c.event, c.dx, c.dy)
+	}
+	recorder.BeginFrame(1, 0.02)
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := NewInputReplayer(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step, ok := replayer.NextFrame(); !ok || step != 0.016 {
+		t.Fatalf("first frame step %v ok=%v", step, ok)
+	}
+
+	for _, c := range cases {
+		event := replayer.PollEvent()
+		if !reflect.DeepEqual(event, c.event) {
+			t.Errorf("%s: replayed %#v, want %#v", c.name, event, c.event)
+		}
+		if motion, ok := event.(*sdl.MouseMotionEvent); ok {
+			dx, dy, ok := replayer.motionDelta(motion)
+			if !ok || dx != c.dx || dy != c.dy {
+				t.Errorf("%s: delta %v,%v ok=%v, want %v,%v", c.name, dx, dy, ok, c.dx, c.dy)
+			}
+		}
+	}
+	if event := replayer.PollEvent(); event != nil {
+		t.Errorf("extra event %#v", event)
+	}
+
+	if step, ok := replayer.NextFrame(); !ok || step != 0.02 || replayer.PollEvent() != nil {
+		t.Errorf("second frame step %v ok=%v", step, ok)
+	}
+	if _, ok := replayer.NextFrame(); ok || !replayer.Finished() {
+		t.Error("replay not finished")
+	}
+}
+
+func TestReplayMouseDelta(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "input.rec")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewInputRecorder(f)
+	recorder.BeginFrame(0, 0.016)
+	recorder.RecordEvent(&sdl.MouseMotionEvent{Type: sdl.MOUSEMOTION, X: 100, Y: 50}, 3.5, -0.25)
+	recorder.Close()
+	f.Close()
+
+	for _, fps := range []bool{false, true} {
+		man, _ := newTestManager(t)
+		log := []string{}
+		a := newTestState("a", &log)
+		man.Setup(a, "test")
+		log = nil
+
+		// Replay started mid-session, last mouse position is off
+		man.FPSMouseModeEnabled = fps
+		man.MouseSampleTaken = true
+		man.LastMouseX, man.LastMouseY = 10, 10
+		if err := man.StartReplay(filename); err != nil {
+			t.Fatal(err)
+		}
+		man.Frame()
+		checkLog(t, &log, "a mouse move 100,50 3.5,-0.25")
+	}
+}
+
+func TestReplayLiveEvents(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "input.rec")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewInputRecorder(f)
+	recorder.BeginFrame(0, 0.016)
+	recorder.RecordEvent(keyEvent(sdl.K_b, true), 0, 0)
+	recorder.Close()
+	f.Close()
+
+	man, platform := newTestManager(t)
+	log := []string{}
+	a := newTestState("a", &log)
+	man.Setup(a, "test")
+	log = nil
+
+	if err := man.StartReplay(filename); err != nil {
+		t.Fatal(err)
+	}
+	platform.PushEvent(keyEvent(sdl.K_a, true), &sdl.ActiveEvent{Gain: 1})
+	man.Frame()
+	checkLog(t, &log, "a event *sdl.ActiveEvent", "a key down 98")
+}
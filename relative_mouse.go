@@ -0,0 +1,162 @@
+package glutils
+
+import (
+	"math"
+)
+
+// Maps pointer speed, in pixels per second, to delta multiplier
+type AccelerationCurve func(speed float32) float32
+
+// No acceleration, deltas are passed as they are
+func NoAcceleration(speed float32) float32 {
+	return 1
+}
+
+// Multiplier grows linearly with speed above threshold, factor is
+// added per pixel per second
+func LinearAcceleration(threshold, factor float32) AccelerationCurve {
+	return func(speed float32) float32 {
+		if speed <= threshold {
+			return 1
+		}
+		return 1 + (speed-threshold)*factor
+	}
+}
+
+// Multiplier is speed relative to baseSpeed raised to given power,
+// 1 below baseSpeed
+func PowerAcceleration(exponent, baseSpeed float32) AccelerationCurve {
+	return func(speed float32) float32 {
+		if speed <= baseSpeed {
+			return 1
+		}
+		return float32(math.Pow(float64(speed/baseSpeed), float64(exponent-1)))
+	}
+}
+
+// Optional interface for states that want filtered relative mouse
+// movement, delivered once per frame while FPS mouse mode is on
+type RelativeMouseHandler interface {
+	OnRelativeMouse(dx, dy float32)
+}
+
+// Filters raw relative mouse deltas. Raw movement of all events in
+// a frame is summed up, accelerated, scaled, inverted and smoothed.
+// Speed and smoothing are measured in time, so results don't depend on
+// frame rate.
+type RelativeMouse struct {
+	// Output units per pixel, radians for use with FpsController
+	SensitivityX, SensitivityY float32
+	InvertX, InvertY           bool
+
+	// Seconds of movement averaged, 0 turns smoothing off
+	SmoothingTime float32
+	// Weight of movement SmoothingTime old relative to the newest, 0..1
+	SmoothingFactor float32
+
+	Acceleration AccelerationCurve
+
+	// Filtered movement of last frame
+	DeltaX, DeltaY float32
+
+	rawX, rawY float32
+	samples    []mouseSample
+}
+
+// Movement rate over one frame
+type mouseSample struct {
+	rateX, rateY float32
+	time         float32
+}
+
+func NewRelativeMouse() *RelativeMouse {
+	return &RelativeMouse{
+		SensitivityX:    0.005,
+		SensitivityY:    0.005,
+		SmoothingFactor: 0.5,
+		Acceleration:    NoAcceleration}
+}
+
+// Add raw movement from single event
+func (self *RelativeMouse) AddRaw(dx, dy float32) {
+	self.rawX += dx
+	self.rawY += dy
+}
+
+// Drop raw movement and smoothing history, used when mode is switched
+func (self *RelativeMouse) Reset() {
+	self.rawX, self.rawY = 0, 0
+	self.DeltaX, self.DeltaY = 0, 0
+	self.samples = nil
+}
+
+// Compute filtered delta from movement gathered since last update,
+// time_step is length of the frame in seconds
+func (self *RelativeMouse) Update(time_step float32) (dx, dy float32) {
+	dx, dy = self.rawX, self.rawY
+	self.rawX, self.rawY = 0, 0
+
+	if self.Acceleration != nil && time_step > 0 {
+		speed := float32(math.Sqrt(float64(dx*dx+dy*dy))) / time_step
+		mul := self.Acceleration(speed)
+		dx *= mul
+		dy *= mul
+	}
+
+	dx *= self.SensitivityX
+	dy *= self.SensitivityY
+	if self.InvertX {
+		dx = -dx
+	}
+	if self.InvertY {
+		dy = -dy
+	}
+
+	if self.SmoothingTime > 0 && time_step > 0 {
+		self.pushSample(mouseSample{dx / time_step, dy / time_step, time_step})
+		rateX, rateY := self.smoothRate()
+		dx, dy = rateX*time_step, rateY*time_step
+	}
+
+	self.DeltaX, self.DeltaY = dx, dy
+	return
+}
+
+// Newest sample goes first, ones older than SmoothingTime are dropped
+func (self *RelativeMouse) pushSample(sample mouseSample) {
+	self.samples = append([]mouseSample{sample}, self.samples...)
+
+	age := float32(0)
+	for i := range self.samples {
+		if age >= self.SmoothingTime {
+			self.samples = self.samples[:i]
+			break
+		}
+		age += self.samples[i].time
+	}
+}
+
+// Average rate weighted by time each sample covers within
+// SmoothingTime, decaying exponentially with age
+func (self *RelativeMouse) smoothRate() (rateX, rateY float32) {
+	sumX, sumY, weights := float32(0), float32(0), float32(0)
+	age := float32(0)
+	for _, sample := range self.samples {
+		span := sample.time
+		if age+span > self.SmoothingTime {
+			span = self.SmoothingTime - age
+		}
+
+		middle := (age + span/2) / self.SmoothingTime
+		weight := span * float32(math.Pow(float64(self.SmoothingFactor), float64(middle)))
+		sumX += sample.rateX * weight
+		sumY += sample.rateY * weight
+		weights += weight
+		age += sample.time
+	}
+
+	if weights == 0 {
+		return self.samples[0].rateX, self.samples[0].rateY
+	}
+	return sumX / weights, sumY / weights
+}
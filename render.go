@@ -5,11 +5,13 @@ import (
 )
 
 func Setup() {
+	defer checkGL("Setup")
 	gl.Enable(gl.CULL_FACE)
 	gl.Disable(gl.LIGHTING)
 	gl.Enable(gl.DEPTH_TEST)
 }
 
 func Clear() {
+	defer checkGL("Clear")
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 }
@@ -26,6 +26,7 @@ func NewShaderRenderOp(blending bool, sprogram *ShaderProgram, conf func(*Shader
 }
 
 func (self *SimpleRenderOp) Render(cam *Camera, m *v.Matrix4) {
+	defer checkGL("SimpleRenderOp.Render")
 	cam.LoadProjection()
 	cam.LoadModelview(m)
 
@@ -71,6 +72,7 @@ func (self *SimpleRenderOp) Render(cam *Camera, m *v.Matrix4) {
 }
 
 func DrawArray(buffer *MeshBuffer) {
+	defer checkGL("DrawArray")
 	vertexSize := buffer.CalcVertexSize()
 
 	gl.EnableClientState(gl.VERTEX_ARRAY)
@@ -110,6 +112,7 @@ func DrawArray(buffer *MeshBuffer) {
 }
 
 func DrawVBO(buffer *MeshBuffer) {
+	defer checkGL("DrawVBO")
 	vertexSize := buffer.CalcVertexSize()
 
 	buffer.VertexBuffer.Bind(gl.ARRAY_BUFFER)
@@ -0,0 +1,225 @@
+package glutils
+
+// Delayed or repeating call, created by Scheduler
+type Timer struct {
+	Owner    AppState
+	Interval float32
+	Repeat   bool
+
+	callback  func()
+	left      float32
+	cancelled bool
+	finished  bool
+}
+
+func (self *Timer) Cancel() {
+	self.cancelled = true
+}
+
+func (self *Timer) Active() bool {
+	return !self.cancelled && !self.finished
+}
+
+func (self *Timer) update(time_step float32) {
+	self.left -= time_step
+	for self.left <= 0 && self.Active() {
+		self.callback()
+
+		if !self.Repeat || self.Interval <= 0 {
+			self.finished = true
+		} else {
+			self.left += self.Interval
+		}
+	}
+}
+
+const (
+	seqDo = iota
+	seqWait
+	seqWaitUntil
+	seqWaitFor
+)
+
+type sequenceStep struct {
+	kind     int
+	action   func()
+	duration float32
+	cond     func() bool
+	other    *Sequence
+}
+
+// Script of actions and waits, built with chained calls like
+//
+//	NewSequence().Do(open).Wait(1).WaitUntil(ready).Do(close)
+//
+// and run by Scheduler.Run
+type Sequence struct {
+	Owner AppState
+
+	steps     []sequenceStep
+	current   int
+	waited    float32
+	cancelled bool
+	finished  bool
+}
+
+func NewSequence() *Sequence {
+	return &Sequence{}
+}
+
+func (self *Sequence) Do(action func()) *Sequence {
+	self.steps = append(self.steps, sequenceStep{kind: seqDo, action: action})
+	return self
+}
+
+// Wait for given number of seconds
+func (self *Sequence) Wait(duration float32) *Sequence {
+	self.steps = append(self.steps, sequenceStep{kind: seqWait, duration: duration})
+	return self
+}
+
+// Wait till cond returns true, it's checked once per update
+func (self *Sequence) WaitUntil(cond func() bool) *Sequence {
+	self.steps = append(self.steps, sequenceStep{kind: seqWaitUntil, cond: cond})
+	return self
+}
+
+// Wait till other sequence is finished or cancelled
+func (self *Sequence) WaitFor(other *Sequence) *Sequence {
+	self.steps = append(self.steps, sequenceStep{kind: seqWaitFor, other: other})
+	return self
+}
+
+func (self *Sequence) Cancel() {
+	self.cancelled = true
+}
+
+func (self *Sequence) Done() bool {
+	return self.cancelled || self.finished
+}
+
+// Run steps until one that has to wait. Time left over after finished
+// wait is passed on to the next one.
+func (self *Sequence) update(time_step float32) {
+	for self.current < len(self.steps) && !self.cancelled {
+		step := &self.steps[self.current]
+		switch step.kind {
+		case seqDo:
+			step.action()
+		case seqWait:
+			self.waited += time_step
+			time_step = 0
+			if self.waited < step.duration {
+				return
+			}
+			time_step = self.waited - step.duration
+			self.waited = 0
+		case seqWaitUntil:
+			if !step.cond() {
+				return
+			}
+		case seqWaitFor:
+			if !step.other.Done() {
+				return
+			}
+		}
+		self.current++
+	}
+	self.finished = true
+}
+
+// Timers and sequences updated with simulation time. Those with owner
+// state stop while it isn't processed and are dropped when it's
+// destroyed.
+type Scheduler struct {
+	timers    []*Timer
+	sequences []*Sequence
+	paused    map[AppState]bool
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{paused: map[AppState]bool{}}
+}
+
+// Call f once after delay seconds, owner may be nil
+func (self *Scheduler) After(owner AppState, delay float32, f func()) *Timer {
+	timer := &Timer{Owner: owner, Interval: delay, callback: f, left: delay}
+	self.timers = append(self.timers, timer)
+	return timer
+}
+
+// Call f every interval seconds until cancelled, owner may be nil
+func (self *Scheduler) Every(owner AppState, interval float32, f func()) *Timer {
+	timer := &Timer{Owner: owner, Interval: interval, Repeat: true,
+		callback: f, left: interval}
+	self.timers = append(self.timers, timer)
+	return timer
+}
+
+// Start sequence, owner may be nil. First steps are run with the next
+// update.
+func (self *Scheduler) Run(owner AppState, seq *Sequence) *Sequence {
+	seq.Owner = owner
+	self.sequences = append(self.sequences, seq)
+	return seq
+}
+
+func (self *Scheduler) Pause(owner AppState) {
+	self.paused[owner] = true
+}
+
+func (self *Scheduler) Resume(owner AppState) {
+	delete(self.paused, owner)
+}
+
+// Cancel all timers and sequences of owner
+func (self *Scheduler) CancelOwner(owner AppState) {
+	for _, timer := range self.timers {
+		if timer.Owner == owner {
+			timer.Cancel()
+		}
+	}
+	for _, seq := range self.sequences {
+		if seq.Owner == owner {
+			seq.Cancel()
+		}
+	}
+	delete(self.paused, owner)
+}
+
+func (self *Scheduler) isPaused(owner AppState) bool {
+	return owner != nil && self.paused[owner]
+}
+
+func (self *Scheduler) Update(time_step float32) {
+	// Callbacks may add new entries, they wait for the next update
+	timers := self.timers
+	for _, timer := range timers {
+		if timer.Active() && !self.isPaused(timer.Owner) {
+			timer.update(time_step)
+		}
+	}
+
+	sequences := self.sequences
+	for _, seq := range sequences {
+		if !seq.Done() && !self.isPaused(seq.Owner) {
+			seq.update(time_step)
+		}
+	}
+
+	activeTimers := []*Timer{}
+	for _, timer := range self.timers {
+		if timer.Active() {
+			activeTimers = append(activeTimers, timer)
+		}
+	}
+	self.timers = activeTimers
+
+	activeSequences := []*Sequence{}
+	for _, seq := range self.sequences {
+		if !seq.Done() {
+			activeSequences = append(activeSequences, seq)
+		}
+	}
+	self.sequences = activeSequences
+}
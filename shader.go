@@ -3,8 +3,6 @@ package glutils
 import (
 	"errors"
 	"github.com/pzsz/gl"
-	"io/ioutil"
-	"os"
 	"strings"
 )
 
@@ -14,6 +12,16 @@ type Shader struct {
 }
 
 func newShader(filename string) (*Shader, error) {
+	source, err := readShaderSource(filename)
+	if err != nil {
+		return nil, err
+	}
+	return compileShader(filename, source)
+}
+
+// Type of shader is guessed from filename
+func compileShader(filename, source string) (*Shader, error) {
+	defer checkGL("compileShader")
 	var shaderType gl.GLenum
 
 	if strings.Index(filename, ".fragment") != -1 {
@@ -24,31 +32,34 @@ func newShader(filename string) (*Shader, error) {
 
 	shader := &Shader{gl.CreateShader(shaderType), filename}
 
-	source, err := readShaderSource(filename)
-	if err != nil {
-		return nil, err
-	}
-
 	shader.ShaderObject.Source(source)
 	shader.ShaderObject.Compile()
 
 	if shader.ShaderObject.Get(gl.COMPILE_STATUS) == gl.FALSE {
 		info := shader.ShaderObject.GetInfoLog()
 		if info != "" {
+			shader.ShaderObject.Delete()
 			return nil, errors.New("Error while compiling GLSL " + filename + ":\n" + info)
 		}
 	}
+	GetResourceRegistry().track(shader, RESOURCE_SHADER, filename, 0).Owner = nil
 	return shader, nil
 }
 
-func readShaderSource(filename string) (string, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return "", err
+// Does nothing when already destroyed
+func (self *Shader) Destroy() {
+	assertMainThread("Shader.Destroy")
+	if self.ShaderObject == 0 {
+		return
 	}
-	defer f.Close()
+	defer checkGL("Shader.Destroy")
+	self.ShaderObject.Delete()
+	self.ShaderObject = 0
+	GetResourceRegistry().untrack(self)
+}
 
-	ret, err := ioutil.ReadAll(f)
+func readShaderSource(filename string) (string, error) {
+	ret, err := GetVFS().ReadFile(filename)
 	if err != nil {
 		return "", err
 	}
@@ -62,6 +73,7 @@ type ShaderProgram struct {
 }
 
 func newShaderProgram(vertex, fragment *Shader) (*ShaderProgram, error) {
+	defer checkGL("newShaderProgram")
 	ret := &ShaderProgram{vertex, fragment, gl.CreateProgram()}
 
 	ret.ProgramObject.AttachShader(vertex.ShaderObject)
@@ -70,6 +82,7 @@ func newShaderProgram(vertex, fragment *Shader) (*ShaderProgram, error) {
 	if ret.ProgramObject.Get(gl.LINK_STATUS) == gl.FALSE {
 		info := ret.ProgramObject.GetInfoLog()
 		if info != "" {
+			ret.ProgramObject.Delete()
 			return nil, errors.New("Error while linking GLSL " + vertex.Filename + " with " + fragment.Filename + ": " + info)
 		}
 	}
@@ -77,21 +90,42 @@ func newShaderProgram(vertex, fragment *Shader) (*ShaderProgram, error) {
 	if ret.ProgramObject.Get(gl.VALIDATE_STATUS) == gl.FALSE {
 		info2 := ret.ProgramObject.GetInfoLog()
 		if info2 != "" {
+			ret.ProgramObject.Delete()
 			return nil, errors.New("Error while linking GLSL " + vertex.Filename + " with " + fragment.Filename + ": " + info2)
 		}
 	}
 
+	GetResourceRegistry().track(ret, RESOURCE_PROGRAM,
+		vertex.Filename+"|"+fragment.Filename, 0).Owner = nil
 	return ret, nil
 }
 
+// Does nothing when already destroyed
+func (self *ShaderProgram) Destroy() {
+	assertMainThread("ShaderProgram.Destroy")
+	if self.ProgramObject == 0 {
+		return
+	}
+	defer checkGL("ShaderProgram.Destroy")
+	self.ProgramObject.Delete()
+	self.ProgramObject = 0
+	GetResourceRegistry().untrack(self)
+}
+
 func (self *ShaderProgram) Use() {
+	assertMainThread("ShaderProgram.Use")
+	defer checkGL("ShaderProgram.Use")
 	self.ProgramObject.Use()
 }
 
 func (self *ShaderProgram) Unuse() {
+	assertMainThread("ShaderProgram.Unuse")
+	defer checkGL("ShaderProgram.Unuse")
 	gl.ProgramUnuse()
 }
 
 func (self *ShaderProgram) GetUniform(name string) gl.UniformLocation {
+	assertMainThread("ShaderProgram.GetUniform")
+	defer checkGL("ShaderProgram.GetUniform")
 	return self.ProgramObject.GetUniformLocation(name)
 }
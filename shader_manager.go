@@ -1,5 +1,7 @@
 package glutils
 
+import "errors"
+
 type ShaderManager struct {
 	Shaders  map[string]*Shader
 	Programs map[string]*ShaderProgram
@@ -7,6 +9,10 @@ type ShaderManager struct {
 
 var shaderManager *ShaderManager = newShaderManager()
 
+func GetShaderManager() *ShaderManager {
+	return shaderManager
+}
+
 func GetProgram(vertexFilename, fragFilename string) (*ShaderProgram, error) {
 	return shaderManager.GetProgram(vertexFilename, fragFilename)
 }
@@ -49,7 +55,48 @@ func (self *ShaderManager) GetProgram(vertexFilename, fragFilename string) (*Sha
 		return nil, err
 	}
 
-	program, err := newShaderProgram(vertexShader, fragmentShader)
+	return self.linkProgram(compositeName, vertexShader, fragmentShader)
+}
+
+// Same as GetProgram, with sources already read. Shaders that are
+// cached don't get compiled again.
+func (self *ShaderManager) programFromSources(vertexFilename, vertexSource, fragFilename, fragSource string) (*ShaderProgram, error) {
+	compositeName := vertexFilename + "|" + fragFilename
+	kept := self.Programs[compositeName]
+	if kept != nil {
+		return kept, nil
+	}
+
+	vertexShader, err := self.shaderFromSource(vertexFilename, vertexSource)
+	if err != nil {
+		return nil, err
+	}
+
+	fragmentShader, err := self.shaderFromSource(fragFilename, fragSource)
+	if err != nil {
+		return nil, err
+	}
+
+	return self.linkProgram(compositeName, vertexShader, fragmentShader)
+}
+
+func (self *ShaderManager) shaderFromSource(filename, source string) (*Shader, error) {
+	kept := self.Shaders[filename]
+	if kept != nil {
+		return kept, nil
+	}
+
+	newShader, err := compileShader(filename, source)
+	if err != nil {
+		return nil, err
+	}
+
+	self.Shaders[filename] = newShader
+	return newShader, nil
+}
+
+func (self *ShaderManager) linkProgram(compositeName string, vertex, fragment *Shader) (*ShaderProgram, error) {
+	program, err := newShaderProgram(vertex, fragment)
 	if err != nil {
 		return nil, err
 	}
@@ -57,3 +104,114 @@ func (self *ShaderManager) GetProgram(vertexFilename, fragFilename string) (*Sha
 	self.Programs[compositeName] = program
 	return program, nil
 }
+
+// Recompile shaders and relink all programs in place, so
+// *ShaderProgram pointers held elsewhere stay valid. Program that
+// fails to build keeps its previous version, first error is returned.
+func (self *ShaderManager) ReloadAll() error {
+	fresh := map[string]*Shader{}
+	var firstErr error
+
+	for _, program := range self.Programs {
+		if err := self.reloadProgram(program, fresh); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// GL keeps old objects alive as long as some program uses them
+	for filename, shader := range fresh {
+		if old := self.Shaders[filename]; old != nil {
+			old.Destroy()
+		}
+		self.Shaders[filename] = shader
+	}
+	return firstErr
+}
+
+func (self *ShaderManager) reloadProgram(program *ShaderProgram, fresh map[string]*Shader) error {
+	vertex, err := freshShader(program.Vertex.Filename, fresh)
+	if err != nil {
+		return err
+	}
+
+	fragment, err := freshShader(program.Fragment.Filename, fresh)
+	if err != nil {
+		return err
+	}
+
+	rebuilt, err := newShaderProgram(vertex, fragment)
+	if err != nil {
+		return err
+	}
+
+	replaceProgram(program, rebuilt)
+	return nil
+}
+
+// Move rebuilt program into the old wrapper
+func replaceProgram(program, rebuilt *ShaderProgram) {
+	program.ProgramObject.Delete()
+	*program = *rebuilt
+	GetResourceRegistry().untrack(rebuilt)
+	// Program now belongs to the old wrapper
+	rebuilt.ProgramObject = 0
+}
+
+// Recompile one shader and relink programs using it in place. When
+// compiling or any of the links fails nothing is changed.
+func (self *ShaderManager) ReloadShader(filename string) error {
+	old := self.Shaders[filename]
+	if old == nil {
+		return errors.New("Shader " + filename + " isn't loaded")
+	}
+
+	shader, err := newShader(filename)
+	if err != nil {
+		return err
+	}
+
+	users := []*ShaderProgram{}
+	rebuilt := []*ShaderProgram{}
+	for _, program := range self.Programs {
+		vertex, fragment := program.Vertex, program.Fragment
+		if vertex.Filename == filename {
+			vertex = shader
+		} else if fragment.Filename == filename {
+			fragment = shader
+		} else {
+			continue
+		}
+
+		fresh, err := newShaderProgram(vertex, fragment)
+		if err != nil {
+			for _, program := range rebuilt {
+				program.Destroy()
+			}
+			shader.Destroy()
+			return err
+		}
+		users = append(users, program)
+		rebuilt = append(rebuilt, fresh)
+	}
+
+	for i, program := range users {
+		replaceProgram(program, rebuilt[i])
+	}
+	old.Destroy()
+	self.Shaders[filename] = shader
+	return nil
+}
+
+// Compile shader once per reload
+func freshShader(filename string, fresh map[string]*Shader) (*Shader, error) {
+	if shader := fresh[filename]; shader != nil {
+		return shader, nil
+	}
+
+	shader, err := newShader(filename)
+	if err != nil {
+		return nil, err
+	}
+	fresh[filename] = shader
+	return shader, nil
+}
@@ -66,6 +66,7 @@ func BuildCubeBuffer(halfSize v.Vector3f) *MeshBuffer {
 
 
 func RenderLine(camera *Camera, m *v.Matrix4, from, to v.Vector3f, colour Colour) {
+	defer checkGL("RenderLine")
 	camera.LoadProjection()
 	camera.LoadModelview(m)
 
@@ -78,6 +79,7 @@ func RenderLine(camera *Camera, m *v.Matrix4, from, to v.Vector3f, colour Colour
 }
 
 func RenderWireQuad(camera *Camera, m *v.Matrix4, size float32, colour Colour) {
+	defer checkGL("RenderWireQuad")
 	camera.LoadProjection()
 	camera.LoadModelview(m)
 
@@ -99,16 +101,19 @@ func RenderWireQuad(camera *Camera, m *v.Matrix4, size float32, colour Colour) {
 }
 
 func RenderUIStart() {
+	defer checkGL("RenderUIStart")
 	gl.Disable(gl.DEPTH_TEST)
 	gl.DepthMask(false)
 }
 
 func RenderUIEnd() {
+	defer checkGL("RenderUIEnd")
 	gl.Enable(gl.DEPTH_TEST)
 	gl.DepthMask(true)
 }
 
 func RenderWireRect(cam *Camera, m *v.Matrix4, size_x, size_y float32, colour Colour) {
+	defer checkGL("RenderWireRect")
 	cam.LoadProjection()
 	cam.LoadModelview(m)
 
@@ -133,6 +138,7 @@ func RenderWireRect(cam *Camera, m *v.Matrix4, size_x, size_y float32, colour Co
 }
 
 func RenderSprite(cam *Camera, m *v.Matrix4, sizeX, sizeY float32, t *Texture) {
+	defer checkGL("RenderSprite")
 	gl.Enable(gl.BLEND)
 	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
 	gl.DepthMask(false)
@@ -146,6 +152,7 @@ func RenderSprite(cam *Camera, m *v.Matrix4, sizeX, sizeY float32, t *Texture) {
 }
 
 func RenderTexturedRect(cam *Camera, m *v.Matrix4, sizeX, sizeY float32, t *Texture) {
+	defer checkGL("RenderTexturedRect")
 	cam.LoadProjection()
 	cam.LoadModelview(m)
 
@@ -172,6 +179,7 @@ func RenderTexturedRect(cam *Camera, m *v.Matrix4, sizeX, sizeY float32, t *Text
 }
 
 func RenderRect(cam *Camera, m *v.Matrix4, sizeX, sizeY float32, c Colour) {
+	defer checkGL("RenderRect")
 	cam.LoadProjection()
 	cam.LoadModelview(m)
 
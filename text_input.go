@@ -0,0 +1,145 @@
+package glutils
+
+import (
+	"github.com/banthar/Go-SDL/sdl"
+	"unicode/utf16"
+)
+
+// Editing operations delivered in text input mode
+const (
+	TEXT_EDIT_BACKSPACE = iota + 1
+	TEXT_EDIT_DELETE
+	TEXT_EDIT_LEFT
+	TEXT_EDIT_RIGHT
+	TEXT_EDIT_WORD_LEFT
+	TEXT_EDIT_WORD_RIGHT
+	TEXT_EDIT_HOME
+	TEXT_EDIT_END
+	TEXT_EDIT_HISTORY_UP
+	TEXT_EDIT_HISTORY_DOWN
+	TEXT_EDIT_COMPLETE
+	TEXT_EDIT_SUBMIT
+	TEXT_EDIT_SELECT_ALL
+	TEXT_EDIT_COPY
+	TEXT_EDIT_CUT
+	TEXT_EDIT_PASTE
+)
+
+type TextEdit struct {
+	Op int
+	// Pasted text, for TEXT_EDIT_PASTE only
+	Text []rune
+}
+
+// Optional interface for states receiving typed text. Text follows
+// keyboard layout, shift state and key repeat, and may hold several
+// runes when input method composes them.
+type TextInputHandler interface {
+	OnTextInput(text []rune)
+}
+
+// Optional interface for states receiving editing operations in text
+// input mode
+type TextEditHandler interface {
+	OnTextEdit(edit TextEdit)
+}
+
+var textEditKeys = map[uint32]int{
+	sdl.K_BACKSPACE: TEXT_EDIT_BACKSPACE,
+	sdl.K_DELETE:    TEXT_EDIT_DELETE,
+	sdl.K_LEFT:      TEXT_EDIT_LEFT,
+	sdl.K_RIGHT:     TEXT_EDIT_RIGHT,
+	sdl.K_HOME:      TEXT_EDIT_HOME,
+	sdl.K_END:       TEXT_EDIT_END,
+	sdl.K_UP:        TEXT_EDIT_HISTORY_UP,
+	sdl.K_DOWN:      TEXT_EDIT_HISTORY_DOWN,
+	sdl.K_TAB:       TEXT_EDIT_COMPLETE,
+	sdl.K_RETURN:    TEXT_EDIT_SUBMIT,
+	sdl.K_KP_ENTER:  TEXT_EDIT_SUBMIT,
+}
+
+// With ctrl held
+var textEditCtrlKeys = map[uint32]int{
+	sdl.K_LEFT:  TEXT_EDIT_WORD_LEFT,
+	sdl.K_RIGHT: TEXT_EDIT_WORD_RIGHT,
+	'a':         TEXT_EDIT_SELECT_ALL,
+	'c':         TEXT_EDIT_COPY,
+	'x':         TEXT_EDIT_CUT,
+	'v':         TEXT_EDIT_PASTE,
+}
+
+// Turns key presses into text and editing operations
+type textInput struct {
+	surrogate rune
+}
+
+// Translate key press. Returns nil text and zero op for keys that are
+// neither, like function keys or escape.
+func (self *textInput) translate(key *sdl.Keysym) (text []rune, op int) {
+	mods := modsFromSdl(key.Mod)
+	// AltGr comes as ctrl with alt, and it does produce text
+	if mods&MOD_CTRL != 0 && mods&MOD_ALT == 0 {
+		return nil, textEditCtrlKeys[key.Sym]
+	}
+
+	if op, found := textEditKeys[key.Sym]; found {
+		return nil, op
+	}
+
+	r := rune(key.Unicode)
+	first := self.surrogate
+	self.surrogate = 0
+
+	switch {
+	case r == 0:
+		return nil, 0
+	case utf16.IsSurrogate(r) && first == 0:
+		// First half of a pair, wait for the other one
+		self.surrogate = r
+		return nil, 0
+	case utf16.IsSurrogate(r):
+		r = utf16.DecodeRune(first, r)
+	case r < 32 || r == 127:
+		return nil, 0
+	}
+	return []rune{r}, 0
+}
+
+// Switch text input mode. While it's on, key presses producing text or
+// editing operations are delivered to TextInputHandler and
+// TextEditHandler states only, instead of OnKeyDown and input actions.
+func (self *AppStateManager) SetTextInput(on bool) {
+	self.TextInputEnabled = on
+	self.textInput = textInput{}
+	self.Platform.SetTextInput(on)
+}
+
+// Returns true when key press was taken as text input
+func (self *AppStateManager) handleTextInput(event sdl.Event, key *sdl.Keysym) bool {
+	text, op := self.textInput.translate(key)
+
+	if text != nil {
+		self.dispatchInput(event, func(state AppState) {
+			if handler, ok := state.(TextInputHandler); ok {
+				handler.OnTextInput(text)
+			}
+		})
+		return true
+	}
+
+	if op != 0 {
+		edit := TextEdit{Op: op}
+		if op == TEXT_EDIT_PASTE && self.Clipboard != nil {
+			edit.Text = []rune(self.Clipboard())
+		}
+
+		self.dispatchInput(event, func(state AppState) {
+			if handler, ok := state.(TextEditHandler); ok {
+				handler.OnTextEdit(edit)
+			}
+		})
+		return true
+	}
+
+	return false
+}
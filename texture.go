@@ -1,12 +1,11 @@
 package glutils
 
 import (
+	"errors"
 	"github.com/pzsz/gl"
 	"image"
 	"image/png"
-	"os"
-	//	"runtime"
-	//	"fmt"
+	"io"
 )
 
 type TexFilterType int
@@ -38,20 +37,35 @@ type Texture struct {
 }
 
 func (self *Texture) Bind(i int) {
+	assertMainThread("Texture.Bind")
+	defer checkGL("Texture.Bind")
 	gl.ActiveTexture(gl.GLenum(gl.TEXTURE0 + i))
 	self.tex.Bind(gl.TEXTURE_2D)
 }
 
 func (self *Texture) Unbind(i int) {
+	assertMainThread("Texture.Unbind")
+	defer checkGL("Texture.Unbind")
 	gl.ActiveTexture(gl.GLenum(gl.TEXTURE0 + i))
 	gl.BindTexture(gl.TEXTURE_2D, 0)
 }
 
+// Does nothing when already destroyed, so GL name that got reused
+// isn't deleted twice
 func (self *Texture) Destroy() {
+	assertMainThread("Texture.Destroy")
+	if self.tex == 0 {
+		return
+	}
+	defer checkGL("Texture.Destroy")
 	self.tex.Delete()
+	self.tex = 0
+	GetResourceRegistry().untrack(self)
 }
 
 func (self *Texture) LoadData(data []uint8) {
+	assertMainThread("Texture.LoadData")
+	defer checkGL("Texture.LoadData")
 	self.tex.Bind(gl.TEXTURE_2D)
 
 	if data == nil {
@@ -69,7 +83,18 @@ func (self *Texture) LoadData(data []uint8) {
 	gl.BindTexture(gl.TEXTURE_2D, 0)
 }
 
+// Copy part of the framebuffer, starting at x,y, into the texture
+func (self *Texture) CopyFromFramebuffer(x, y int) {
+	assertMainThread("Texture.CopyFromFramebuffer")
+	defer checkGL("Texture.CopyFromFramebuffer")
+	self.tex.Bind(gl.TEXTURE_2D)
+	gl.CopyTexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, x, y, self.Width, self.Height)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+}
+
 func (self *Texture) setupParams() {
+	assertMainThread("Texture.setupParams")
+	defer checkGL("Texture.setupParams")
 	self.tex.Bind(gl.TEXTURE_2D)
 
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
@@ -130,6 +155,7 @@ func (self *TextureManager) GetTextureEx(name string, newSetup TexSetup) (*Textu
 		return nil, er
 	}
 
+	GetResourceRegistry().SetOwner(tex, nil)
 	self.Textures[name] = tex
 
 	return tex, nil
@@ -142,6 +168,7 @@ func (self *TextureManager) CreateEmptyTexture(name string, width, height int, s
 	texture := &Texture{t, name, width, height, setup}
 	texture.LoadData(nil)
 	texture.setupParams()
+	GetResourceRegistry().track(texture, RESOURCE_TEXTURE, name, textureBytes(texture))
 
 	return texture
 }
@@ -164,27 +191,64 @@ func getByteArray(img image.Image) (ret []byte) {
 }
 
 func (self *TextureManager) loadTexture(filename string, setup TexSetup) (*Texture, error) {
-	//	runtime.LockOSThread()
-
-	f, er := os.Open(filename)
+	f, er := GetVFS().Open(filename)
 	if er != nil {
 		return nil, er
 	}
 	defer f.Close()
-	img, er := png.Decode(f)
+
+	bytes, width, height, er := decodeTexture(f)
 	if er != nil {
 		return nil, er
 	}
 
-	bytes := getByteArray(img)
+	return uploadTexture(filename, bytes, width, height, setup), nil
+}
 
-	width, height := img.Bounds().Max.X, img.Bounds().Max.Y
+// Load texture file again into the same GL object, so *Texture
+// pointers stay valid. Texture is left as it was when loading fails.
+func (self *TextureManager) ReloadTexture(name string) error {
+	texture := self.Textures[name]
+	if texture == nil {
+		return errors.New("Texture " + name + " isn't loaded")
+	}
 
+	f, er := GetVFS().Open(name)
+	if er != nil {
+		return er
+	}
+	defer f.Close()
+
+	bytes, width, height, er := decodeTexture(f)
+	if er != nil {
+		return er
+	}
+
+	texture.Width, texture.Height = width, height
+	texture.LoadData(bytes)
+	texture.setupParams()
+	GetResourceRegistry().resize(texture, textureBytes(texture))
+	return nil
+}
+
+// Decode PNG into RGBA bytes, doesn't touch GL
+func decodeTexture(r io.Reader) (bytes []byte, width, height int, er error) {
+	img, er := png.Decode(r)
+	if er != nil {
+		return nil, 0, 0, er
+	}
+
+	width, height = img.Bounds().Max.X, img.Bounds().Max.Y
+	return getByteArray(img), width, height, nil
+}
+
+func uploadTexture(name string, bytes []byte, width, height int, setup TexSetup) *Texture {
 	t := gl.GenTexture()
 
-	texture := &Texture{t, filename, width, height, setup}
+	texture := &Texture{t, name, width, height, setup}
 	texture.LoadData(bytes)
 	texture.setupParams()
+	GetResourceRegistry().track(texture, RESOURCE_TEXTURE, name, textureBytes(texture))
 
-	return texture, nil
+	return texture
 }
@@ -0,0 +1,230 @@
+package glutils
+
+import (
+	"github.com/pzsz/gl"
+)
+
+// Timed effect played while states are switched. Effects are drawn
+// only over RenderingStates, other states just get the switch delayed.
+type Transition interface {
+	// Length in seconds
+	Duration() float32
+	// Progress, from 0 to 1, at which the stack change is applied
+	SwitchPoint() float32
+
+	// Called before anything is changed
+	Begin(man *AppStateManager)
+	// Draw over rendered states
+	Render(progress float32)
+	// Called when transition is finished
+	End()
+}
+
+type runningTransition struct {
+	trans    Transition
+	op       stateOp
+	elapsed  float32
+	switched bool
+	done     func()
+}
+
+func (self *runningTransition) progress() float32 {
+	duration := self.trans.Duration()
+	if duration <= 0 || self.elapsed >= duration {
+		return 1
+	}
+	return self.elapsed / duration
+}
+
+// Push state using transition, done is called when it's over
+func (self *AppStateManager) PushWith(state AppState, trans Transition, done func()) {
+	self.startTransition(stateOp{opPush, state}, trans, done)
+}
+
+// Pop running state using transition, done is called when it's over
+func (self *AppStateManager) PopWith(trans Transition, done func()) {
+	self.startTransition(stateOp{opPop, nil}, trans, done)
+}
+
+// Replace running state using transition, done is called when it's over
+func (self *AppStateManager) ReplaceWith(state AppState, trans Transition, done func()) {
+	self.startTransition(stateOp{opReplace, state}, trans, done)
+}
+
+func (self *AppStateManager) InTransition() bool {
+	return self.transition != nil
+}
+
+func (self *AppStateManager) startTransition(op stateOp, trans Transition, done func()) {
+	// Only one at a time, rush the previous one
+	if self.transition != nil {
+		self.finishTransition()
+	}
+
+	trans.Begin(self)
+	self.transition = &runningTransition{trans: trans, op: op, done: done}
+	self.updateTransition(0)
+}
+
+func (self *AppStateManager) updateTransition(time_step float32) {
+	t := self.transition
+	if t == nil {
+		return
+	}
+
+	t.elapsed += time_step
+	if !t.switched && t.progress() >= t.trans.SwitchPoint() {
+		t.switched = true
+		self.queue(t.op)
+	}
+
+	if t.progress() >= 1 {
+		self.finishTransition()
+	}
+}
+
+func (self *AppStateManager) finishTransition() {
+	t := self.transition
+	self.transition = nil
+
+	if !t.switched {
+		t.switched = true
+		self.queue(t.op)
+	}
+
+	t.trans.End()
+	if t.done != nil {
+		t.done()
+	}
+}
+
+// Fade to colour, switch states and fade back in
+type FadeTransition struct {
+	Time   float32
+	Colour Colour
+}
+
+func NewFadeTransition(time float32, colour Colour) *FadeTransition {
+	return &FadeTransition{time, colour}
+}
+
+func (self *FadeTransition) Duration() float32 {
+	return self.Time
+}
+
+func (self *FadeTransition) SwitchPoint() float32 {
+	return 0.5
+}
+
+func (self *FadeTransition) Begin(man *AppStateManager) {
+}
+
+func (self *FadeTransition) Render(progress float32) {
+	alpha := progress * 2
+	if progress > 0.5 {
+		alpha = (1 - progress) * 2
+	}
+
+	c := self.Colour
+	c.A = byte(float32(c.A) * alpha)
+	renderScreenQuad(nil, c)
+}
+
+func (self *FadeTransition) End() {
+}
+
+// Blend last frame of previous states into the new ones
+type CrossFadeTransition struct {
+	Time float32
+
+	snapshot *Texture
+}
+
+func NewCrossFadeTransition(time float32) *CrossFadeTransition {
+	return &CrossFadeTransition{Time: time}
+}
+
+func (self *CrossFadeTransition) Duration() float32 {
+	return self.Time
+}
+
+func (self *CrossFadeTransition) SwitchPoint() float32 {
+	return 0
+}
+
+func (self *CrossFadeTransition) Begin(man *AppStateManager) {
+	states := man.renderedStates()
+	if len(states) == 0 {
+		return
+	}
+
+	// Draw current states once more and keep the picture
+	man.renderStates(states, 1)
+
+	vp := GetViewport()
+	self.snapshot = GetTextureManager().CreateEmptyTexture("crossfade",
+		int(vp.Width), int(vp.Height), NO_MIPMAP_TEXSETUP)
+	self.snapshot.CopyFromFramebuffer(0, 0)
+	// Transition outlives the state running now, End frees snapshot
+	GetResourceRegistry().SetOwner(self.snapshot, nil)
+}
+
+func (self *CrossFadeTransition) Render(progress float32) {
+	if self.snapshot == nil {
+		return
+	}
+	renderScreenQuad(self.snapshot, Colour{255, 255, 255, byte(255 * (1 - progress))})
+}
+
+func (self *CrossFadeTransition) End() {
+	if self.snapshot != nil {
+		self.snapshot.Destroy()
+		self.snapshot = nil
+	}
+}
+
+// Draw quad covering whole viewport, with texture bottom-up as
+// it comes from framebuffer
+func renderScreenQuad(t *Texture, c Colour) {
+	defer checkGL("renderScreenQuad")
+	vp := GetViewport()
+	cam := NewCamera(vp)
+	cam.SetOrthoProjection(-1, 1)
+	cam.SetModelviewOne()
+
+	cam.LoadProjection()
+	cam.LoadModelview(&cam.ModelviewMatrix)
+
+	RenderUIStart()
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+
+	if t != nil {
+		gl.Enable(gl.TEXTURE_2D)
+		t.tex.Bind(gl.TEXTURE_2D)
+	}
+
+	gl.Color4ub(c.R, c.G, c.B, c.A)
+	gl.Begin(gl.QUADS)
+	gl.TexCoord2f(0, 0)
+	gl.Vertex3f(0, 0, 0)
+
+	gl.TexCoord2f(1, 0)
+	gl.Vertex3f(vp.Width, 0, 0)
+
+	gl.TexCoord2f(1, 1)
+	gl.Vertex3f(vp.Width, vp.Height, 0)
+
+	gl.TexCoord2f(0, 1)
+	gl.Vertex3f(0, vp.Height, 0)
+	gl.End()
+
+	if t != nil {
+		gl.Disable(gl.TEXTURE_2D)
+		gl.BindTexture(gl.TEXTURE_2D, 0)
+	}
+
+	gl.Disable(gl.BLEND)
+	RenderUIEnd()
+	gl.Color4ub(255, 255, 255, 255)
+}
@@ -0,0 +1,157 @@
+package glutils
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+type vfsMount struct {
+	point  string
+	fsys   fs.FS
+	closer io.Closer
+}
+
+// Filesystem all managers read assets from. Directories, zip archives
+// and embed.FS are mounted under mount points, and a file present in
+// several mounts is taken from the one mounted last, so mod directories
+// mounted after base pack override its content. Names not found in
+// any mount are opened as plain OS paths.
+type VFS struct {
+	lock   sync.RWMutex
+	mounts []vfsMount
+}
+
+var vfsInstance *VFS = NewVFS()
+
+func NewVFS() *VFS {
+	return &VFS{}
+}
+
+func GetVFS() *VFS {
+	return vfsInstance
+}
+
+// Mount fsys under point, empty point mounts it at the root. For
+// embed.FS holding a subdirectory use fs.Sub to strip it.
+func (self *VFS) Mount(point string, fsys fs.FS) {
+	self.mount(vfsMount{point: cleanVfsPath(point), fsys: fsys})
+}
+
+func (self *VFS) MountDir(point, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New(dir + " is not a directory")
+	}
+
+	self.Mount(point, os.DirFS(dir))
+	return nil
+}
+
+// Archive stays open till it's unmounted
+func (self *VFS) MountZip(point, filename string) error {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+
+	self.mount(vfsMount{cleanVfsPath(point), archive, archive})
+	return nil
+}
+
+func (self *VFS) mount(m vfsMount) {
+	self.lock.Lock()
+	self.mounts = append(self.mounts, m)
+	self.lock.Unlock()
+}
+
+// Remove everything mounted under point
+func (self *VFS) Unmount(point string) {
+	point = cleanVfsPath(point)
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	kept := []vfsMount{}
+	for _, m := range self.mounts {
+		if m.point != point {
+			kept = append(kept, m)
+		} else if m.closer != nil {
+			m.closer.Close()
+		}
+	}
+	self.mounts = kept
+}
+
+func (self *VFS) Open(name string) (fs.File, error) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	clean := cleanVfsPath(name)
+	for i := len(self.mounts) - 1; i >= 0; i-- {
+		m := self.mounts[i]
+		rel, ok := m.relative(clean)
+		if !ok {
+			continue
+		}
+
+		f, err := m.fsys.Open(rel)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return os.Open(name)
+}
+
+func (self *VFS) ReadFile(name string) ([]byte, error) {
+	f, err := self.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+func (self *VFS) Stat(name string) (fs.FileInfo, error) {
+	f, err := self.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.Stat()
+}
+
+// Name inside mounted filesystem, false when name is outside the mount
+func (self *vfsMount) relative(name string) (string, bool) {
+	switch {
+	case self.point == ".":
+		return name, true
+	case name == self.point:
+		return ".", true
+	case strings.HasPrefix(name, self.point+"/"):
+		return name[len(self.point)+1:], true
+	}
+	return "", false
+}
+
+// Slash separated path without leading slash or dots, "." for root
+func cleanVfsPath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
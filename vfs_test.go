@@ -0,0 +1,46 @@
+package glutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVFSOpen(t *testing.T) {
+	dir := t.TempDir()
+	osFile := filepath.Join(dir, "plain.txt")
+	if err := os.WriteFile(osFile, []byte("os"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	vfs := NewVFS()
+	vfs.Mount("", fstest.MapFS{
+		"data/a.txt": {Data: []byte("base a")},
+		"data/b.txt": {Data: []byte("base b")},
+	})
+	vfs.Mount("data", fstest.MapFS{
+		"b.txt": {Data: []byte("mod b")},
+	})
+
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"data/a.txt", "base a"},
+		{"data/b.txt", "mod b"},
+		{"/data/../data/b.txt", "mod b"},
+		{osFile, "os"},
+	}
+
+	for _, c := range cases {
+		data, err := vfs.ReadFile(c.name)
+		if err != nil || string(data) != c.want {
+			t.Errorf("%s: got %q err=%v, want %q", c.name, data, err, c.want)
+		}
+	}
+
+	if _, err := vfs.Open("data/missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("missing file: err=%v", err)
+	}
+}
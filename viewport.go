@@ -17,8 +17,14 @@ func GetViewport() *Viewport {
 }
 
 func (self *Viewport) SetScreenSize(w, h float32) {
+	defer checkGL("Viewport.SetScreenSize")
+	self.SetSize(w, h)
+	gl.Viewport(0, 0, int(w), int(h))
+}
+
+// Update size without touching GL viewport
+func (self *Viewport) SetSize(w, h float32) {
 	self.Width = w
 	self.Height = h
 	self.Aspect = w / h
-	gl.Viewport(0, 0, int(w), int(h))
 }
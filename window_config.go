@@ -0,0 +1,35 @@
+package glutils
+
+// Video mode and GL context setup
+type WindowConfig struct {
+	Width, Height int
+	Fullscreen    bool
+	Resizable     bool
+	BitsPerPixel  int
+
+	DepthBits   int
+	StencilBits int
+	// Multisampling samples per pixel, 0 turns it off
+	Samples int
+	// Sync buffer swaps with display refresh
+	VSync bool
+}
+
+func DefaultWindowConfig() WindowConfig {
+	return WindowConfig{
+		Width:        800,
+		Height:       600,
+		Resizable:    true,
+		BitsPerPixel: 32,
+		DepthBits:    24}
+}
+
+// Both new video mode and restoring the previous one failed
+type VideoModeError struct {
+	Err        error
+	RestoreErr error
+}
+
+func (self *VideoModeError) Error() string {
+	return self.Err.Error() + ", couldn't restore video mode: " + self.RestoreErr.Error()
+}